@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// actionsSummarySize is the number of top contributors listed in the job
+// summary.
+const actionsSummarySize = 10
+
+// appendToFile appends s to the file at path, creating it if needed.
+func appendToFile(path string, s string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %s", path)
+	}
+	if _, err := f.WriteString(s); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "error writing %s", path)
+	}
+	return errors.Wrapf(f.Close(), "error closing %s", path)
+}
+
+// writeActionsOutputs writes a job summary of the top contributors to
+// $GITHUB_STEP_SUMMARY and sets the contributor_count, contribution_count
+// and output_file step outputs in $GITHUB_OUTPUT. Either is skipped if its
+// environment variable is unset, i.e. when not running in GitHub Actions.
+func writeActionsOutputs(
+	users map[string]user, start time.Time, end time.Time, outputFile string,
+) error {
+	ranked := rankContributors(users, start, end)
+	total := 0
+	for _, entry := range ranked {
+		total += entry.count
+	}
+
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+		top := ranked
+		if len(top) > actionsSummarySize {
+			top = top[:actionsSummarySize]
+		}
+		lines := []string{
+			"## External Contributors",
+			"",
+			fmt.Sprintf("%d contributors, %d %s.", len(ranked), total, countNoun("commits")),
+			"",
+		}
+		for i, entry := range top {
+			lines = append(lines, fmt.Sprintf("%d. %s (%d)", i+1, escapeMarkdown(entry.u.name), entry.count))
+		}
+		if err := appendToFile(path, strings.Join(lines, "\n")+"\n"); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("* GITHUB_STEP_SUMMARY is not set, skipping job summary\n")
+	}
+
+	if path := os.Getenv("GITHUB_OUTPUT"); path != "" {
+		outputs := fmt.Sprintf(
+			"contributor_count=%d\ncontribution_count=%d\noutput_file=%s\n",
+			len(ranked),
+			total,
+			outputFile,
+		)
+		if err := appendToFile(path, outputs); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("* GITHUB_OUTPUT is not set, skipping step outputs\n")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// authorsLinePattern matches a well formed AUTHORS line: a name followed by
+// one or more emails in angle brackets, optionally annotated with a login in
+// parentheses.
+var authorsLinePattern = regexp.MustCompile(
+	`^[^<>\s][^<>]* <[^<>\s]+@[^<>\s]+>( <[^<>\s]+@[^<>\s]+>)*( \([A-Za-z0-9][A-Za-z0-9-]*\))?$`,
+)
+
+// lintAuthors returns the anomalies in an AUTHORS file which would degrade
+// filtering: malformed lines, organization emails without a name, and
+// emails listed more than once. seen maps emails to where they were first
+// listed, so duplicates across files are found.
+func lintAuthors(path string, contents string, seen map[string]string) []string {
+	var findings []string
+	for i, line := range strings.Split(contents, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		where := fmt.Sprintf("%s:%d", path, i+1)
+		if !authorsLinePattern.MatchString(line) {
+			findings = append(findings, fmt.Sprintf("%s: not of the form \"Name <email>\": %q", where, line))
+		}
+		if strings.Contains(line, "@cockroachlabs.com") && strings.HasPrefix(strings.TrimSpace(line), "<") {
+			findings = append(findings, fmt.Sprintf("%s: cockroachlabs.com email without a name: %q", where, line))
+		}
+		for _, field := range strings.Fields(line) {
+			if !strings.HasPrefix(field, "<") || !strings.HasSuffix(field, ">") {
+				continue
+			}
+			email := strings.ToLower(field[1 : len(field)-1])
+			if first, ok := seen[email]; ok {
+				findings = append(findings, fmt.Sprintf("%s: duplicate email %s, first listed at %s", where, email, first))
+				continue
+			}
+			seen[email] = where
+		}
+	}
+	return findings
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// loginPatterns matches logins against a list of entries, where an entry is
+// either an exact login or a pattern in which "*" matches any sequence of
+// characters (e.g. "*[bot]" or "dependabot*"). Every other character,
+// including brackets, matches literally.
+type loginPatterns struct {
+	// entries holds every entry, in the order given.
+	entries  []string
+	exact    map[string]struct{}
+	patterns map[string]*regexp.Regexp
+}
+
+// parseLoginPatterns parses a comma separated list of logins and patterns.
+func parseLoginPatterns(s string) loginPatterns {
+	ret := loginPatterns{
+		exact:    map[string]struct{}{},
+		patterns: map[string]*regexp.Regexp{},
+	}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		ret.entries = append(ret.entries, entry)
+		if !strings.Contains(entry, "*") {
+			ret.exact[entry] = struct{}{}
+			continue
+		}
+		re := "^" + strings.ReplaceAll(regexp.QuoteMeta(entry), `\*`, ".*") + "$"
+		ret.patterns[entry] = regexp.MustCompile(re)
+	}
+	return ret
+}
+
+func (p loginPatterns) matches(login string) bool {
+	_, ok := p.match(login)
+	return ok
+}
+
+// match returns the first entry which matches login, if any.
+func (p loginPatterns) match(login string) (string, bool) {
+	if _, ok := p.exact[login]; ok {
+		return login, true
+	}
+	for _, entry := range p.entries {
+		if re, ok := p.patterns[entry]; ok && re.MatchString(login) {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// nameBlocklist holds the names which exclude a contributor: the names in
+// the AUTHORS files, and the logins of organization members, which a
+// contributor's name may also match.
+type nameBlocklist struct {
+	authorsNames map[string]struct{}
+	memberLogins map[string]struct{}
+}
+
+// match returns which list name is in, if any.
+func (b nameBlocklist) match(name string) (string, bool) {
+	if _, ok := b.authorsNames[name]; ok {
+		return "in AUTHORS", true
+	}
+	if _, ok := b.memberLogins[name]; ok {
+		return "the login of an organization member", true
+	}
+	return "", false
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestParseLoginPatterns(t *testing.T) {
+	p := parseLoginPatterns(" exact , *[bot], dependabot*,")
+	for _, tc := range []struct {
+		login string
+		ok    bool
+	}{
+		{login: "exact", ok: true},
+		{login: "exactly", ok: false},
+		{login: "renovate[bot]", ok: true},
+		// Brackets match literally rather than as a character class.
+		{login: "renovateb", ok: false},
+		{login: "dependabot", ok: true},
+		{login: "dependabot-preview", ok: true},
+		{login: "not-dependabot", ok: false},
+		{login: "", ok: false},
+	} {
+		if p.matches(tc.login) != tc.ok {
+			t.Errorf("matches(%q) = %t, expected %t", tc.login, !tc.ok, tc.ok)
+		}
+	}
+}
+
+func TestNameBlocklistMatch(t *testing.T) {
+	b := nameBlocklist{
+		authorsNames: map[string]struct{}{"Named Employee": {}},
+		memberLogins: map[string]struct{}{"staffer": {}},
+	}
+	for _, tc := range []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{name: "Named Employee", expected: "in AUTHORS", ok: true},
+		{name: "staffer", expected: "the login of an organization member", ok: true},
+		{name: "External Contributor"},
+	} {
+		if got, ok := b.match(tc.name); got != tc.expected || ok != tc.ok {
+			t.Errorf("match(%q) = %q, %t; expected %q, %t", tc.name, got, ok, tc.expected, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// isExcludedBranch returns whether branch is in -exclude_branches.
+func isExcludedBranch(branch string) bool {
+	for _, excluded := range strings.Split(*flagExcludeBranches, ",") {
+		if excluded = strings.TrimSpace(excluded); excluded != "" && excluded == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedBranches returns the branches of the repo which are not in
+// -exclude_branches.
+func allowedBranches(
+	ctx context.Context, ghClient *github.Client, org string, repo string,
+) ([]string, error) {
+	opts := &github.BranchListOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	var branches []string
+	for {
+		page, resp, err := ghClient.Repositories.ListBranches(ctx, org, repo, opts)
+		if err != nil {
+			return nil, errors.Wrapf(markGithubError(err), "error listing branches for %s", repo)
+		}
+		for _, branch := range page {
+			if !isExcludedBranch(branch.GetName()) {
+				branches = append(branches, branch.GetName())
+			}
+		}
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// allowedBranchCommits returns the SHAs of the commits on the repo's
+// branches which are not in -exclude_branches, listed with the given
+// options. They are only used to check which commits are on an allowed
+// branch; the commits themselves are not counted.
+func allowedBranchCommits(
+	ctx context.Context, ghClient *github.Client, org string, repo string, opts github.CommitsListOptions,
+) (map[string]struct{}, error) {
+	branches, err := allowedBranches(ctx, ghClient, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	shas := map[string]struct{}{}
+	for _, branch := range branches {
+		opts.SHA = branch
+		opts.Page = 0
+		for {
+			commits, resp, err := ghClient.Repositories.ListCommits(ctx, org, repo, &opts)
+			if err != nil {
+				return nil, errors.Wrapf(markGithubError(err), "error listing commits on branch %s of %s", branch, repo)
+			}
+			for _, commit := range commits {
+				shas[commit.GetSHA()] = struct{}{}
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	return shas, nil
+}
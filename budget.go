@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// Values for -on_budget_exceeded.
+const (
+	budgetWarn  = "warn"
+	budgetAbort = "abort"
+	budgetWait  = "wait"
+)
+
+// estimateRequests roughly estimates the number of requests scanning the
+// commits of repos since start makes, from the number of commits in each.
+// Looking up contributors and scanning reviews, discussions and forks are
+// not accounted for.
+func estimateRequests(
+	ctx context.Context, ghClient *github.Client, org string, repos []string, start time.Time,
+) (int, error) {
+	total := 0
+	for _, repo := range repos {
+		// With a page size of one, the last page is the number of commits.
+		commits, resp, err := ghClient.Repositories.ListCommits(
+			ctx,
+			org,
+			repo,
+			&github.CommitsListOptions{
+				Since:       start,
+				ListOptions: github.ListOptions{PerPage: 1},
+			},
+		)
+		if err != nil {
+			return 0, errors.Wrapf(markGithubError(err), "error counting commits for %s", repo)
+		}
+		n := resp.LastPage
+		if n == 0 {
+			n = len(commits)
+		}
+		if *flagMaxCommitsPerRepo > 0 && n > *flagMaxCommitsPerRepo {
+			n = *flagMaxCommitsPerRepo
+		}
+		total += (n + 99) / 100
+		if *flagExcludePaths != "" {
+			total += n
+		}
+		if *flagResolveSquashAuthors {
+			total += n
+		}
+	}
+	return total, nil
+}
+
+// checkBudget compares the estimated requests against the remaining rate
+// limit, and if they exceed it, warns, aborts or waits for the limit to
+// reset according to onExceeded.
+func checkBudget(
+	ctx context.Context,
+	ghClient *github.Client,
+	org string,
+	repos []string,
+	start time.Time,
+	onExceeded string,
+) error {
+	estimate, err := estimateRequests(ctx, ghClient, org, repos, start)
+	if err != nil {
+		return err
+	}
+	limits, _, err := ghClient.RateLimits(ctx)
+	if err != nil {
+		return errors.Wrap(markGithubError(err), "error getting rate limits")
+	}
+	core := limits.GetCore()
+	fmt.Printf(
+		"* Scan estimated at %d requests, %d of %d remaining until %s\n",
+		estimate,
+		core.Remaining,
+		core.Limit,
+		core.Reset.Time.Format(time.RFC3339),
+	)
+	if estimate <= core.Remaining {
+		return nil
+	}
+	switch onExceeded {
+	case budgetWarn:
+		fmt.Printf("* WARNING: the scan will likely exceed the rate limit and wait for it to reset\n")
+	case budgetAbort:
+		return errors.Mark(
+			errors.Newf("estimated %d requests exceeds the %d remaining", estimate, core.Remaining),
+			ErrRateLimited,
+		)
+	case budgetWait:
+		wait := time.Until(core.Reset.Time) + time.Second
+		fmt.Printf("* Waiting %s for the rate limit to reset\n", wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if estimate > core.Limit {
+			fmt.Printf("* WARNING: the scan will likely exceed even a full rate limit of %d\n", core.Limit)
+		}
+	default:
+		return errors.Newf("unknown -on_budget_exceeded %q", onExceeded)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// checkpoint is the progress of a scan restored from a checkpoint file,
+// allowing an interrupted scan to resume from the last page it successfully
+// processed.
+type checkpoint struct {
+	CompletedRepos []string
+	Repo           string
+	NextPage       int
+}
+
+// A checkpoint file is a log of JSON records, one per line, which is only
+// ever appended to while scanning, so saving progress after every page does
+// not rewrite every contribution found so far. After each page, the
+// contributions it found are appended followed by a progress record; any
+// contributions not followed by a progress record are from a page which was
+// not completed, and are ignored.
+type checkpointRecord struct {
+	Contribution *checkpointContribution `json:"contribution,omitempty"`
+	Progress     *checkpointProgress     `json:"progress,omitempty"`
+}
+
+type checkpointContribution struct {
+	Login string    `json:"login"`
+	URL   string    `json:"url,omitempty"`
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Repo  string    `json:"repo"`
+	SHA   string    `json:"sha"`
+	Time  time.Time `json:"time"`
+	// Alumni is set if the login was recorded as alumni, per
+	// -annotate_alumni.
+	Alumni bool `json:"alumni,omitempty"`
+}
+
+// checkpointProgress records that every page of the repo before NextPage
+// has been processed, or with a NextPage of 0, that the repo has been
+// completed. Excluded holds the exclusion counts so far.
+type checkpointProgress struct {
+	Repo     string         `json:"repo"`
+	NextPage int            `json:"next_page"`
+	Excluded map[string]int `json:"excluded"`
+}
+
+// apply updates the checkpoint with the given progress. Progress without a
+// repo only carries the exclusion counts.
+func (c *checkpoint) apply(p checkpointProgress) {
+	if p.Repo == "" {
+		return
+	}
+	c.Repo = p.Repo
+	c.NextPage = p.NextPage
+	if p.NextPage == 0 {
+		c.CompletedRepos = append(c.CompletedRepos, p.Repo)
+		c.Repo = ""
+	}
+}
+
+// loadCheckpoint restores the scan progress from path, if it exists, then
+// compacts the file.
+func (s *scanner) loadCheckpoint(path string) error {
+	s.checkpointPath = path
+	s.checkpoint = checkpoint{}
+	s.checkpointed = 0
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "error reading checkpoint %s", path)
+	}
+	var contributions, pending []checkpointContribution
+	var excluded map[string]int
+	lines := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+	for i, line := range lines {
+		var r checkpointRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			// A crash mid-append can leave the last record truncated.
+			if i == len(lines)-1 {
+				break
+			}
+			return errors.Wrapf(err, "error parsing checkpoint %s on line %d", path, i+1)
+		}
+		switch {
+		case r.Contribution != nil:
+			pending = append(pending, *r.Contribution)
+		case r.Progress != nil:
+			contributions = append(contributions, pending...)
+			pending = nil
+			excluded = r.Progress.Excluded
+			s.checkpoint.apply(*r.Progress)
+		default:
+			return errors.Newf("error parsing checkpoint %s: unrecognized record on line %d", path, i+1)
+		}
+	}
+	for _, c := range contributions {
+		if _, ok := s.users[c.Login]; !ok {
+			s.users[c.Login] = &github.User{
+				Login:   github.String(c.Login),
+				HTMLURL: github.String(c.URL),
+			}
+		}
+		s.userTimes[c.Login] = append(s.userTimes[c.Login], c.Time)
+		if c.Alumni {
+			s.alumni[c.Login] = struct{}{}
+		}
+		s.seenSHAs[c.SHA] = struct{}{}
+		s.contributions = append(s.contributions, contribution{
+			login: c.Login,
+			name:  c.Name,
+			email: c.Email,
+			repo:  c.Repo,
+			sha:   c.SHA,
+			t:     c.Time,
+		})
+	}
+	for reason, count := range excluded {
+		s.excluded[reason] += count
+	}
+	return s.compactCheckpoint()
+}
+
+// compactCheckpoint rewrites the checkpoint with only the records needed to
+// restore the current progress, dropping any records of incomplete pages.
+func (s *scanner) compactCheckpoint() error {
+	var b bytes.Buffer
+	if err := s.encodeCheckpointContributions(&b, s.contributions); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(&b)
+	for _, repo := range s.checkpoint.CompletedRepos {
+		if err := enc.Encode(checkpointRecord{Progress: &checkpointProgress{Repo: repo}}); err != nil {
+			return err
+		}
+	}
+	if err := enc.Encode(checkpointRecord{Progress: &checkpointProgress{
+		Repo:     s.checkpoint.Repo,
+		NextPage: s.checkpoint.NextPage,
+		Excluded: s.excluded,
+	}}); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash mid-write cannot corrupt
+	// the existing checkpoint.
+	tmpPath := s.checkpointPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "error writing checkpoint %s", tmpPath)
+	}
+	if err := os.Rename(tmpPath, s.checkpointPath); err != nil {
+		return errors.Wrapf(err, "error writing checkpoint %s", s.checkpointPath)
+	}
+	s.checkpointed = len(s.contributions)
+	return nil
+}
+
+// encodeCheckpointContributions writes a record of each contribution to b.
+func (s *scanner) encodeCheckpointContributions(b *bytes.Buffer, contributions []contribution) error {
+	enc := json.NewEncoder(b)
+	for _, c := range contributions {
+		_, alumni := s.alumni[c.login]
+		if err := enc.Encode(checkpointRecord{Contribution: &checkpointContribution{
+			Login:  c.login,
+			URL:    s.users[c.login].GetHTMLURL(),
+			Name:   c.name,
+			Email:  c.email,
+			Repo:   c.repo,
+			SHA:    c.sha,
+			Time:   c.t,
+			Alumni: alumni,
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repoCompleted returns whether the checkpoint records repo as fully
+// scanned.
+func (s *scanner) repoCompleted(repo string) bool {
+	for _, completed := range s.checkpoint.CompletedRepos {
+		if completed == repo {
+			return true
+		}
+	}
+	return false
+}
+
+// saveCheckpoint records that every page of repo before nextPage has been
+// processed. A nextPage of 0 marks the repo as completed. Only the
+// contributions found since the last save are appended.
+func (s *scanner) saveCheckpoint(repo string, nextPage int) error {
+	if s.checkpointPath == "" {
+		return nil
+	}
+	var b bytes.Buffer
+	if err := s.encodeCheckpointContributions(&b, s.contributions[s.checkpointed:]); err != nil {
+		return err
+	}
+	progress := checkpointProgress{
+		Repo:     repo,
+		NextPage: nextPage,
+		Excluded: s.excluded,
+	}
+	if err := json.NewEncoder(&b).Encode(checkpointRecord{Progress: &progress}); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.checkpointPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "error opening checkpoint %s", s.checkpointPath)
+	}
+	// The page's records are appended in a single write, so a crash can at
+	// worst truncate them, which loadCheckpoint tolerates.
+	if _, err := f.Write(b.Bytes()); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "error writing checkpoint %s", s.checkpointPath)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "error writing checkpoint %s", s.checkpointPath)
+	}
+	s.checkpointed = len(s.contributions)
+	s.checkpoint.apply(progress)
+	return nil
+}
+
+// clearCheckpoint removes the checkpoint once the scan has completed.
+func (s *scanner) clearCheckpoint() error {
+	if s.checkpointPath == "" {
+		return nil
+	}
+	if err := os.Remove(s.checkpointPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error removing checkpoint %s", s.checkpointPath)
+	}
+	return nil
+}
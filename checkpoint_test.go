@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	when := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	record := func(s *scanner, sha string, login string) {
+		if err := s.processCommit(context.Background(), nil, "cockroach", testCommit(sha, login, login+"@example.com", when)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := testScanner()
+	if err := s.loadCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	record(s, "a", "alice")
+	if err := s.saveCheckpoint("docs", 0); err != nil {
+		t.Fatal(err)
+	}
+	record(s, "b", "bob")
+	s.exclude(reasonOrgMember)
+	if err := s.saveCheckpoint("cockroach", 2); err != nil {
+		t.Fatal(err)
+	}
+	before, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(s, "c", "carol")
+	if err := s.saveCheckpoint("cockroach", 3); err != nil {
+		t.Fatal(err)
+	}
+	after, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(after, before) {
+		t.Error("expected saving a page to only append to the checkpoint")
+	}
+	if got := bytes.Count(after, []byte(`"contribution"`)); got != 3 {
+		t.Errorf("expected each contribution to be written once, got %d", got)
+	}
+
+	// Simulate a crash partway through appending the next page.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"contribution":{"login":"dave","sha":"d"}}` + "\n" + `{"progress":{"repo":"cock`); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := testScanner()
+	if err := resumed.loadCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := resumed.preview(10); got != "  1. alice (1)\n  2. bob (1)\n  3. carol (1)" {
+		t.Errorf("unexpected contributors restored:\n%s", got)
+	}
+	if !resumed.repoCompleted("docs") || resumed.repoCompleted("cockroach") {
+		t.Errorf("expected only docs to be completed, got %v", resumed.checkpoint.CompletedRepos)
+	}
+	if resumed.checkpoint.Repo != "cockroach" || resumed.checkpoint.NextPage != 3 {
+		t.Errorf("expected to resume cockroach from page 3, got %s page %d", resumed.checkpoint.Repo, resumed.checkpoint.NextPage)
+	}
+	if got := resumed.excluded[reasonOrgMember]; got != 1 {
+		t.Errorf("expected 1 org member exclusion restored, got %d", got)
+	}
+
+	// Loading compacts away the incomplete page, so the log stays valid as
+	// the resumed scan appends to it.
+	record(resumed, "e", "erin")
+	if err := resumed.saveCheckpoint("cockroach", 0); err != nil {
+		t.Fatal(err)
+	}
+	final := testScanner()
+	if err := final.loadCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(final.contributions) != 4 || !final.repoCompleted("cockroach") {
+		t.Errorf("expected 4 contributions and cockroach completed, got %d and %v",
+			len(final.contributions), final.checkpoint.CompletedRepos)
+	}
+}
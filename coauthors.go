@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+)
+
+// coauthorTrailer matches the Co-authored-by trailers GitHub recognizes in
+// commit messages.
+var coauthorTrailer = regexp.MustCompile(`(?mi)^co-authored-by:[ \t]*(.*?)[ \t]*<([^>\s]+)>[ \t]*$`)
+
+// noreplyEmail matches the noreply emails GitHub uses for commits made on
+// its web interface, e.g. 1234+login@users.noreply.github.com.
+var noreplyEmail = regexp.MustCompile(`(?i)^(?:\d+\+)?([a-z0-9-]+)@users\.noreply\.github\.com$`)
+
+type coauthor struct {
+	name  string
+	email string
+}
+
+// parseCoauthors returns the co-authors named in the commit message's
+// Co-authored-by trailers, once each.
+func parseCoauthors(message string) []coauthor {
+	var coauthors []coauthor
+	seen := map[string]struct{}{}
+	for _, m := range coauthorTrailer.FindAllStringSubmatch(message, -1) {
+		email := strings.ToLower(m[2])
+		if _, ok := seen[email]; ok {
+			continue
+		}
+		seen[email] = struct{}{}
+		coauthors = append(coauthors, coauthor{name: m[1], email: m[2]})
+	}
+	return coauthors
+}
+
+// coauthorCommit returns a copy of the commit attributed to the given
+// co-author, so co-authors are filtered and counted just like authors. The
+// co-author is only linked to a GitHub account if their email is a GitHub
+// noreply email; otherwise they are treated as an unlinked author.
+func coauthorCommit(commit *github.RepositoryCommit, c coauthor) *github.RepositoryCommit {
+	author := &github.User{Name: github.String(c.name)}
+	if m := noreplyEmail.FindStringSubmatch(c.email); m != nil {
+		author.Login = github.String(m[1])
+	}
+	date := commit.GetCommit().GetAuthor().GetDate()
+	return &github.RepositoryCommit{
+		SHA:    commit.SHA,
+		Author: author,
+		Commit: &github.Commit{
+			Author: &github.CommitAuthor{
+				Name:  github.String(c.name),
+				Email: github.String(c.email),
+				Date:  &date,
+			},
+			Message: commit.GetCommit().Message,
+			Parents: commit.GetCommit().Parents,
+		},
+	}
+}
+
+// checkCoauthors returns a copy of the commit for each of its co-authors to
+// credit. With -exclude_coauthored_internal, co-authors face the same filters
+// as commit authors. The caller must hold s.mu.
+func (s *scanner) checkCoauthors(commit *github.RepositoryCommit) []*github.RepositoryCommit {
+	var credited []*github.RepositoryCommit
+	for _, c := range parseCoauthors(commit.GetCommit().GetMessage()) {
+		if strings.EqualFold(c.email, commit.GetCommit().GetAuthor().GetEmail()) {
+			continue
+		}
+		coauthored := coauthorCommit(commit, c)
+		reason := ""
+		if commitLogin(coauthored) == "" {
+			reason = reasonUnlinkedAuthor
+		} else if *flagExcludeCoauthoredInternal {
+			if external, r := s.isExternal(coauthored); !external {
+				reason = r
+			}
+		}
+		if reason != "" {
+			fmt.Printf("* not crediting co-author %s of commit %s: %s\n", c.email, commit.GetSHA(), reason)
+			continue
+		}
+		credited = append(credited, coauthored)
+	}
+	return credited
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+)
+
+func TestParseCoauthors(t *testing.T) {
+	message := `sql: fix the thing
+
+Co-authored-by: Jane Doe <1234+jane@users.noreply.github.com>
+co-authored-by:Bob <bob@example.com>
+Co-authored-by: Jane Again <1234+JANE@users.noreply.github.com>
+Not-a-trailer: Carol <carol@example.com>`
+
+	var got []string
+	for _, c := range parseCoauthors(message) {
+		got = append(got, c.name+" <"+c.email+">")
+	}
+	expected := "Jane Doe <1234+jane@users.noreply.github.com>,Bob <bob@example.com>"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("parseCoauthors() = %s, expected %s", strings.Join(got, ","), expected)
+	}
+}
+
+func creditedLogins(credited []*github.RepositoryCommit) string {
+	var logins []string
+	for _, c := range credited {
+		logins = append(logins, commitLogin(c))
+	}
+	sort.Strings(logins)
+	return strings.Join(logins, ",")
+}
+
+func TestCheckCommitCoauthors(t *testing.T) {
+	*flagCountCoauthors = true
+	defer func() { *flagCountCoauthors = false }()
+
+	when := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	mixed := func(sha string, login string, email string) *github.RepositoryCommit {
+		commit := testCommit(sha, login, email, when)
+		commit.Commit.Message = github.String(`pair on the fix
+
+Co-authored-by: Employee <employee@cockroachlabs.com>
+Co-authored-by: Former <former@example.com>
+Co-authored-by: Member <42+member@users.noreply.github.com>
+Co-authored-by: External <7+external@users.noreply.github.com>
+Co-authored-by: Unlinked <unlinked@example.com>`)
+		return commit
+	}
+	newTestScanner := func() *scanner {
+		s := testScanner()
+		s.organizationMembers = map[string]*github.User{"member": {Login: github.String("member")}}
+		s.emails = map[string]struct{}{"former@example.com": {}}
+		return s
+	}
+
+	for _, tc := range []struct {
+		name            string
+		excludeInternal bool
+		resolveUnlinked bool
+		commit          *github.RepositoryCommit
+		expected        string
+	}{
+		{
+			name:            "unfiltered",
+			resolveUnlinked: true,
+			commit:          mixed("a", "outsider", "outsider@example.com"),
+			expected:        "external,member,outsider,unlinked:employee@cockroachlabs.com,unlinked:former@example.com,unlinked:unlinked@example.com",
+		},
+		{
+			name:            "unlinked co-authors need -resolve_unlinked",
+			excludeInternal: true,
+			commit:          mixed("b", "outsider", "outsider@example.com"),
+			expected:        "external,outsider",
+		},
+		{
+			name:            "internal co-authors excluded",
+			excludeInternal: true,
+			resolveUnlinked: true,
+			commit:          mixed("c", "outsider", "outsider@example.com"),
+			expected:        "external,outsider,unlinked:unlinked@example.com",
+		},
+		{
+			name:            "internal author with external co-authors",
+			excludeInternal: true,
+			resolveUnlinked: true,
+			commit:          mixed("d", "employee", "employee@cockroachlabs.com"),
+			expected:        "external,unlinked:unlinked@example.com",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			*flagExcludeCoauthoredInternal = tc.excludeInternal
+			*flagResolveUnlinked = tc.resolveUnlinked
+			defer func() {
+				*flagExcludeCoauthoredInternal = false
+				*flagResolveUnlinked = false
+			}()
+			if got := creditedLogins(newTestScanner().checkCommit(tc.commit)); got != tc.expected {
+				t.Errorf("checkCommit() credited %s, expected %s", got, tc.expected)
+			}
+		})
+	}
+}
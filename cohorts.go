@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// formatCohorts renders a retention table grouping contributors by the year
+// of their first contribution between start and end. Each cell is the
+// percentage of the cohort active, i.e. with at least one contribution, in
+// the given number of years after their first.
+func formatCohorts(users map[string]user, start time.Time, end time.Time) string {
+	type cohort struct {
+		size   int
+		active []int
+	}
+	numYears := end.Year() - start.Year() + 1
+	cohorts := map[int]*cohort{}
+	for _, u := range users {
+		activeYears := map[int]struct{}{}
+		firstYear := 0
+		for _, t := range u.times {
+			if !t.After(start) || !t.Before(end) {
+				continue
+			}
+			activeYears[t.Year()] = struct{}{}
+			if firstYear == 0 || t.Year() < firstYear {
+				firstYear = t.Year()
+			}
+		}
+		if firstYear == 0 {
+			continue
+		}
+		c, ok := cohorts[firstYear]
+		if !ok {
+			c = &cohort{active: make([]int, end.Year()-firstYear+1)}
+			cohorts[firstYear] = c
+		}
+		c.size++
+		for year := range activeYears {
+			c.active[year-firstYear]++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("| Cohort | Contributors |")
+	for i := 0; i < numYears; i++ {
+		fmt.Fprintf(&b, " Year %d |", i)
+	}
+	b.WriteString("\n| --- | --- |")
+	for i := 0; i < numYears; i++ {
+		b.WriteString(" --- |")
+	}
+	b.WriteString("\n")
+	for year := start.Year(); year <= end.Year(); year++ {
+		c, ok := cohorts[year]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "| %d | %d |", year, c.size)
+		for i := 0; i < numYears; i++ {
+			if i < len(c.active) {
+				fmt.Fprintf(&b, " %.0f%% |", 100*float64(c.active[i])/float64(c.size))
+			} else {
+				b.WriteString(" |")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,18 @@
+package main
+
+import "strings"
+
+// normalizeCompany tidies a self-reported company, dropping the leading @
+// of organization handles, e.g. "@cockroachdb" becomes "cockroachdb".
+func normalizeCompany(company string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(company), "@"))
+}
+
+// displayCompany returns the company shown for a contributor, which is
+// nothing without -show_company.
+func displayCompany(company string) string {
+	if !*flagShowCompany {
+		return ""
+	}
+	return company
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// reposForOrg returns the repos of org given in -repos as "org/repo".
+func reposForOrg(org string) []string {
+	var repos []string
+	for _, entry := range repoList() {
+		if i := strings.Index(entry, "/"); i >= 0 && strings.EqualFold(entry[:i], org) {
+			repos = append(repos, entry[i+1:])
+		}
+	}
+	return repos
+}
+
+// scanOrg scans the given repos of org, returning the external
+// contributors found. Only org membership, the blocklist and the allowlist
+// are used to tell who is external, as the AUTHORS files are specific to
+// one organization.
+func scanOrg(
+	ctx context.Context,
+	ghClient *github.Client,
+	org string,
+	repos []string,
+	start time.Time,
+	end time.Time,
+) (map[string]user, error) {
+	members, err := getOrganizationLogins(ctx, ghClient, org)
+	if err != nil {
+		return nil, err
+	}
+	s := newScanner(
+		org,
+		start,
+		end,
+		members,
+		map[string]struct{}{},
+		map[string]struct{}{},
+		parseLoginPatterns(*flagBlocklist),
+		parseLoginPatterns(*flagAllowlist),
+	)
+	for _, repo := range repos {
+		if err := s.scanRepo(ctx, ghClient, repo); err != nil {
+			return nil, err
+		}
+	}
+	users := map[string]user{}
+	for login, times := range s.userTimes {
+		users[login] = user{login: login, name: login, times: times}
+	}
+	return users, nil
+}
+
+// compareOrgs scans each of the organizations, with the same client and so
+// the same caches and rate limit handling, and renders a Markdown table
+// comparing their external contributors and commits, all-time and by year.
+func compareOrgs(
+	ctx context.Context, ghClient *github.Client, orgs []string, start time.Time, end time.Time,
+) (string, error) {
+	var results []map[string]user
+	for _, org := range orgs {
+		repos := reposForOrg(org)
+		if len(repos) == 0 {
+			return "", errors.Newf("no repos given for %s; with -compare_orgs, give -repos as org/repo", org)
+		}
+		users, err := scanOrg(ctx, ghClient, org, repos, start, end)
+		if err != nil {
+			return "", err
+		}
+		results = append(results, users)
+	}
+
+	header := []string{""}
+	separator := []string{"---"}
+	for _, org := range orgs {
+		header = append(header, escapeMarkdown(org)+" contributors", escapeMarkdown(org)+" "+countNoun("commits"))
+		separator = append(separator, "---:", "---:")
+	}
+	lines := []string{
+		"| " + strings.Join(header, " | ") + " |",
+		"| " + strings.Join(separator, " | ") + " |",
+	}
+	row := func(label string, from time.Time, to time.Time) {
+		cells := []string{label}
+		for _, users := range results {
+			ranked := rankContributors(users, from, to)
+			total := 0
+			for _, entry := range ranked {
+				total += entry.count
+			}
+			cells = append(cells, fmt.Sprintf("%d", len(ranked)), fmt.Sprintf("%d", total))
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+	}
+	row("All-Time", start, end)
+	for _, year := range reportYears(start, end) {
+		yearFrom, yearTo := yearRange(year)
+		row(fmt.Sprintf("%d", year), yearFrom, yearTo)
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
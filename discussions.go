@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// discussionsQuery fetches a page of a repo's discussions, most recently
+// updated first, along with their first page of comments. The REST API does not cover
+// discussions, hence GraphQL.
+const discussionsQuery = `query($owner: String!, $name: String!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    discussions(first: 50, after: $cursor, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        createdAt
+        updatedAt
+        author { login }
+        comments(first: 100) {
+          nodes { createdAt author { login } }
+        }
+      }
+    }
+  }
+}`
+
+type discussionPost struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Author    struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+type discussionsResponse struct {
+	Data struct {
+		Repository struct {
+			Discussions struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []struct {
+					discussionPost
+					UpdatedAt time.Time `json:"updatedAt"`
+					Comments  struct {
+						Nodes []discussionPost `json:"nodes"`
+					} `json:"comments"`
+				} `json:"nodes"`
+			} `json:"discussions"`
+		} `json:"repository"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// scanDiscussions records discussions and discussion comments posted by
+// external contributors in the given repo within the scan window. Only the
+// first 100 comments of each discussion are counted. Wikis are not counted
+// as GitHub exposes no API for their history.
+func (s *scanner) scanDiscussions(ctx context.Context, ghClient *github.Client, repo string) error {
+	fmt.Printf("* Looking at discussions for repo %s\n", repo)
+	var cursor *string
+	for {
+		// GraphQL lives beside rather than under the REST API on GitHub
+		// Enterprise Server (/api/graphql vs /api/v3/), and at the root of
+		// api.github.com, which "../graphql" resolves to in both cases.
+		req, err := ghClient.NewRequest("POST", "../graphql", map[string]interface{}{
+			"query": discussionsQuery,
+			"variables": map[string]interface{}{
+				"owner":  s.org,
+				"name":   repo,
+				"cursor": cursor,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		var resp discussionsResponse
+		if _, err := ghClient.Do(ctx, req, &resp); err != nil {
+			if retry, err := waitForRateLimit(ctx, err); err != nil {
+				return err
+			} else if retry {
+				continue
+			}
+			return errors.Wrapf(err, "error listing discussions for %s", repo)
+		}
+		if len(resp.Errors) > 0 {
+			return errors.Newf("error listing discussions for %s: %s", repo, resp.Errors[0].Message)
+		}
+		discussions := resp.Data.Repository.Discussions
+		for _, discussion := range discussions.Nodes {
+			s.recordDiscussionPost(discussion.discussionPost)
+			for _, comment := range discussion.Comments.Nodes {
+				s.recordDiscussionPost(comment)
+			}
+		}
+		// Comments may be newer than their discussion, so discussions are
+		// ordered by when they were last updated, which includes commenting,
+		// and the rest can be skipped once they were last updated before the
+		// window.
+		if !discussions.PageInfo.HasNextPage || len(discussions.Nodes) == 0 ||
+			discussions.Nodes[len(discussions.Nodes)-1].UpdatedAt.Before(s.start) {
+			return nil
+		}
+		cursor = &discussions.PageInfo.EndCursor
+	}
+}
+
+func (s *scanner) recordDiscussionPost(post discussionPost) {
+	if post.CreatedAt.Before(s.start) || post.CreatedAt.After(s.end) {
+		return
+	}
+	if !s.isExternalLogin(post.Author.Login) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.discussions[post.Author.Login] = append(s.discussions[post.Author.Login], post.CreatedAt)
+}
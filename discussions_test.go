@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestScanDiscussionsOrderedByUpdate(t *testing.T) {
+	pages := []string{
+		`{"data": {"repository": {"discussions": {
+			"pageInfo": {"hasNextPage": true, "endCursor": "page2"},
+			"nodes": [
+				{
+					"createdAt": "2019-06-01T00:00:00Z",
+					"updatedAt": "2020-06-02T00:00:00Z",
+					"author": {"login": "asker"},
+					"comments": {"nodes": [{"createdAt": "2020-06-02T00:00:00Z", "author": {"login": "answerer"}}]}
+				},
+				{
+					"createdAt": "2020-03-01T00:00:00Z",
+					"updatedAt": "2020-03-01T00:00:00Z",
+					"author": {"login": "poster"},
+					"comments": {"nodes": []}
+				}
+			]
+		}}}}`,
+		`{"data": {"repository": {"discussions": {
+			"pageInfo": {"hasNextPage": true, "endCursor": "page3"},
+			"nodes": [
+				{
+					"createdAt": "2019-01-01T00:00:00Z",
+					"updatedAt": "2019-12-01T00:00:00Z",
+					"author": {"login": "old"},
+					"comments": {"nodes": []}
+				}
+			]
+		}}}}`,
+	}
+	var cursors []interface{}
+	ghClient := testGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		cursors = append(cursors, body.Variables["cursor"])
+		if len(cursors) > len(pages) {
+			t.Fatalf("unexpected request for cursor %v", body.Variables["cursor"])
+		}
+		fmt.Fprint(w, pages[len(cursors)-1])
+	}))
+
+	s := testScanner()
+	if err := s.scanDiscussions(context.Background(), ghClient, "cockroach"); err != nil {
+		t.Fatal(err)
+	}
+	if len(cursors) != 2 {
+		t.Errorf("expected to stop after the page updated before the window, requested %v", cursors)
+	}
+	for _, login := range []string{"answerer", "poster"} {
+		if len(s.discussions[login]) != 1 {
+			t.Errorf("expected one post by %s, got %v", login, s.discussions)
+		}
+	}
+	if len(s.discussions) != 2 {
+		t.Errorf("expected posts by answerer and poster only, got %v", s.discussions)
+	}
+}
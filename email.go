@@ -0,0 +1,44 @@
+package main
+
+import "strings"
+
+// displayEmail returns what is shown of a contributor's email: the full
+// address with -show_full_email, only the domain with -show_email_domain,
+// and otherwise nothing. The email may already be just a domain, if that is
+// all the intermediate file recorded.
+func displayEmail(email string) string {
+	if *flagShowFullEmail {
+		return email
+	}
+	if *flagShowEmailDomain {
+		return emailDomain(email)
+	}
+	return ""
+}
+
+// emailDomain returns the domain of the email, or the email itself if it
+// is already just a domain.
+func emailDomain(email string) string {
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		return email[i+1:]
+	}
+	return email
+}
+
+// recordedEmails returns what is recorded in the intermediate file of each
+// login's email: nothing unless -show_email_domain or -show_full_email is
+// set, and only the domain without -show_full_email, so no more is stored
+// than is shown.
+func recordedEmails(emails map[string]string) map[string]string {
+	if *flagShowFullEmail {
+		return emails
+	}
+	if !*flagShowEmailDomain {
+		return nil
+	}
+	domains := make(map[string]string, len(emails))
+	for login, email := range emails {
+		domains[login] = emailDomain(email)
+	}
+	return domains
+}
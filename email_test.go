@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestRecordedEmails(t *testing.T) {
+	emails := map[string]string{"alice": "alice@example.com"}
+	defer func() {
+		*flagShowEmailDomain = false
+		*flagShowFullEmail = false
+	}()
+
+	if got := recordedEmails(emails); got != nil {
+		t.Errorf("expected no emails to be recorded by default, got %v", got)
+	}
+
+	*flagShowEmailDomain = true
+	got := recordedEmails(emails)
+	if got["alice"] != "example.com" {
+		t.Errorf("expected only the domain to be recorded, got %v", got)
+	}
+	// A recorded domain is shown as is.
+	if shown := displayEmail(got["alice"]); shown != "example.com" {
+		t.Errorf("expected the recorded domain to be shown, got %q", shown)
+	}
+
+	*flagShowFullEmail = true
+	if got := recordedEmails(emails); got["alice"] != "alice@example.com" {
+		t.Errorf("expected the full email to be recorded, got %v", got)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// Errors which distinguish failure modes, to be checked with errors.Is.
+var (
+	// ErrRateLimited marks errors caused by GitHub's primary or secondary
+	// rate limits.
+	ErrRateLimited = errors.New("rate limited by GitHub")
+	// ErrUnauthorized marks errors caused by a missing, invalid or
+	// insufficiently scoped token.
+	ErrUnauthorized = errors.New("not authorized by GitHub")
+	// ErrRepoNotFound marks errors caused by a repo which does not exist or
+	// is not visible to the token.
+	ErrRepoNotFound = errors.New("repo not found")
+)
+
+// markGithubError marks err, as returned from a go-github call, with the
+// error describing its failure mode, if known. A not found error is
+// assumed to mean the repo being looked at does not exist.
+func markGithubError(err error) error {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+		return errors.Mark(err, ErrRateLimited)
+	}
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		switch respErr.Response.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return errors.Mark(err, ErrUnauthorized)
+		case http.StatusNotFound:
+			return errors.Mark(err, ErrRepoNotFound)
+		}
+	}
+	return err
+}
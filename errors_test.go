@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+func TestMarkGithubError(t *testing.T) {
+	responseErr := func(status int) error {
+		return errors.Wrap(&github.ErrorResponse{Response: &http.Response{StatusCode: status}}, "error getting repo")
+	}
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "rate limit", err: &github.RateLimitError{}, expected: ErrRateLimited},
+		{name: "abuse rate limit", err: &github.AbuseRateLimitError{}, expected: ErrRateLimited},
+		{name: "unauthorized", err: responseErr(http.StatusUnauthorized), expected: ErrUnauthorized},
+		{name: "forbidden", err: responseErr(http.StatusForbidden), expected: ErrUnauthorized},
+		{name: "not found", err: responseErr(http.StatusNotFound), expected: ErrRepoNotFound},
+		{name: "server error", err: responseErr(http.StatusBadGateway)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := markGithubError(tc.err)
+			for _, sentinel := range []error{ErrRateLimited, ErrUnauthorized, ErrRepoNotFound} {
+				if got, expected := errors.Is(err, sentinel), sentinel == tc.expected; got != expected {
+					t.Errorf("errors.Is(%v, %v) = %t, expected %t", err, sentinel, got, expected)
+				}
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected the marked error to wrap %v", tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// etagEntry is a previously fetched response body and the ETag it was
+// served with.
+type etagEntry struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// etagCache persists responses to GET requests along with their ETags.
+type etagCache struct {
+	mu      sync.Mutex
+	entries map[string]etagEntry
+}
+
+func newETagCache() *etagCache {
+	return &etagCache{entries: map[string]etagEntry{}}
+}
+
+func loadETagCache(path string) (*etagCache, error) {
+	c := newETagCache()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, errors.Wrapf(err, "error reading etag cache %s", path)
+	}
+	if err := json.Unmarshal(b, &c.entries); err != nil {
+		return nil, errors.Wrapf(err, "error parsing etag cache %s", path)
+	}
+	return c, nil
+}
+
+func (c *etagCache) save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, b, 0644), "error writing etag cache %s", path)
+}
+
+// etagTransport makes GET requests conditional on the ETag of the cached
+// response, if any. A 304 Not Modified response does not count against the
+// rate limit, and is replayed to the caller as the cached response so
+// unchanged pages are processed exactly as if they had been refetched.
+type etagTransport struct {
+	base  http.RoundTripper
+	cache *etagCache
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+	key := req.Header.Get("Accept") + " " + req.URL.String()
+	t.cache.mu.Lock()
+	entry, cached := t.cache.entries[key]
+	t.cache.mu.Unlock()
+	if cached {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if cached && resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close()
+		header := entry.Header.Clone()
+		// Keep the fresh rate limit headers rather than the cached ones.
+		for k, v := range resp.Header {
+			header[k] = v
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Header = header
+		resp.Body = ioutil.NopCloser(bytes.NewReader(entry.Body))
+		resp.ContentLength = int64(len(entry.Body))
+		return resp, nil
+	}
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	t.cache.mu.Lock()
+	t.cache.entries[key] = etagEntry{ETag: etag, Header: resp.Header.Clone(), Body: body}
+	t.cache.mu.Unlock()
+	return resp, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// scanForks records commits by external contributors which are on the
+// default branch of up to -max_forks of the repo's forks, most watched
+// first, but not upstream. Commits already seen upstream are deduplicated by
+// SHA. GitHub only returns the first 250 commits a fork is ahead by.
+func (s *scanner) scanForks(ctx context.Context, ghClient *github.Client, repo string) error {
+	upstream, _, err := ghClient.Repositories.Get(ctx, s.org, repo)
+	if err != nil {
+		return errors.Wrapf(err, "error getting repo %s", repo)
+	}
+	opts := &github.RepositoryListForksOptions{
+		Sort: "watchers",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	var forks []*github.Repository
+	for len(forks) < *flagMaxForks {
+		page, resp, err := ghClient.Repositories.ListForks(ctx, s.org, repo, opts)
+		if err != nil {
+			return errors.Wrapf(err, "error listing forks for %s", repo)
+		}
+		forks = append(forks, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(forks) > *flagMaxForks {
+		forks = forks[:*flagMaxForks]
+	}
+	for _, fork := range forks {
+		if isExcludedBranch(fork.GetDefaultBranch()) {
+			fmt.Printf("* Skipping fork %s, its default branch %s is excluded\n", fork.GetFullName(), fork.GetDefaultBranch())
+			continue
+		}
+		fmt.Printf("* Looking at fork %s of repo %s\n", fork.GetFullName(), repo)
+		comparison, _, err := ghClient.Repositories.CompareCommits(
+			ctx,
+			s.org,
+			repo,
+			upstream.GetDefaultBranch(),
+			fmt.Sprintf("%s:%s", fork.GetOwner().GetLogin(), fork.GetDefaultBranch()),
+		)
+		if err != nil {
+			// Forks may have diverged so far that they can't be compared, or
+			// been deleted since they were listed.
+			fmt.Printf("* WARNING: cannot compare fork %s: %v\n", fork.GetFullName(), err)
+			continue
+		}
+		for _, commit := range comparison.Commits {
+			// Fork commits are in the upstream repo's network, so can be
+			// looked up through it.
+			if err := s.processCommit(ctx, ghClient, repo, commit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// publishGist publishes the file at path to a secret gist, updating the
+// gist whose ID is stored in statePath if there is one, and otherwise
+// creating one and storing its ID there.
+func publishGist(ctx context.Context, ghClient *github.Client, path string, statePath string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "error reading %s", path)
+	}
+	gist := &github.Gist{
+		Description: github.String("External Contributors - Hall of Fame"),
+		Public:      github.Bool(false),
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(filepath.Base(path)): {Content: github.String(string(content))},
+		},
+	}
+
+	var id string
+	if b, err := ioutil.ReadFile(statePath); err == nil {
+		id = strings.TrimSpace(string(b))
+	} else if !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error reading %s", statePath)
+	}
+
+	var published *github.Gist
+	if id != "" {
+		published, _, err = ghClient.Gists.Edit(ctx, id, gist)
+		if err != nil {
+			return errors.Wrapf(err, "error updating gist %s", id)
+		}
+	} else {
+		published, _, err = ghClient.Gists.Create(ctx, gist)
+		if err != nil {
+			return errors.Wrap(err, "error creating gist")
+		}
+		if err := ioutil.WriteFile(statePath, []byte(published.GetID()+"\n"), 0644); err != nil {
+			return errors.Wrapf(err, "error writing %s", statePath)
+		}
+	}
+	fmt.Printf("* Published to %s\n", published.GetHTMLURL())
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// hashFilePath returns the path of the sidecar file holding the hash of the
+// content last written to path.
+func hashFilePath(path string) string {
+	return path + ".hash"
+}
+
+func contentHash(content string) string {
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:])
+}
+
+// contentUnchanged returns whether hash matches the hash last recorded for
+// path. A missing output or sidecar file counts as changed, so a deleted
+// output is written again.
+func contentUnchanged(path string, hash string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error checking %s", path)
+	}
+	b, err := ioutil.ReadFile(hashFilePath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error reading hash for %s", path)
+	}
+	return strings.TrimSpace(string(b)) == hash, nil
+}
+
+// writeContentHash records hash as the hash of the content written to path.
+func writeContentHash(path string, hash string) error {
+	return errors.Wrapf(
+		ioutil.WriteFile(hashFilePath(path), []byte(hash+"\n"), 0644),
+		"error writing hash for %s",
+		path,
+	)
+}
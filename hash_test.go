@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContentUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.md")
+	hash := contentHash("content")
+
+	if unchanged, err := contentUnchanged(path, hash); err != nil || unchanged {
+		t.Fatalf("expected content without a recorded hash to be changed, got (%t, %v)", unchanged, err)
+	}
+	if err := ioutil.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeContentHash(path, hash); err != nil {
+		t.Fatal(err)
+	}
+	if unchanged, err := contentUnchanged(path, hash); err != nil || !unchanged {
+		t.Fatalf("expected content matching the recorded hash to be unchanged, got (%t, %v)", unchanged, err)
+	}
+	if unchanged, err := contentUnchanged(path, contentHash("other")); err != nil || unchanged {
+		t.Fatalf("expected different content to be changed, got (%t, %v)", unchanged, err)
+	}
+
+	// A deleted output is written again even though its hash was recorded.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if unchanged, err := contentUnchanged(path, hash); err != nil || unchanged {
+		t.Fatalf("expected a missing output to be changed, got (%t, %v)", unchanged, err)
+	}
+}
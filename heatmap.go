@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+type heatmapDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// heatmapDays returns the number of contributions by all users on each UTC
+// day between start and end, including days without any.
+func heatmapDays(users map[string]user, start time.Time, end time.Time) []heatmapDay {
+	counts := map[string]int{}
+	for _, u := range users {
+		for _, t := range u.times {
+			if t.After(start) && t.Before(end) {
+				counts[t.UTC().Format("2006-01-02")]++
+			}
+		}
+	}
+	var days []heatmapDay
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	for day := first; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		days = append(days, heatmapDay{Date: date, Count: counts[date]})
+	}
+	return days
+}
+
+// writeHeatmap writes the contributions per day to path, for a calendar
+// heatmap: as CSV if path ends in .csv, otherwise as a JSON array.
+func writeHeatmap(path string, users map[string]user, start time.Time, end time.Time) error {
+	days := heatmapDays(users, start, end)
+	var b []byte
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		lines := []string{"date,count"}
+		for _, day := range days {
+			lines = append(lines, fmt.Sprintf("%s,%d", day.Date, day.Count))
+		}
+		b = []byte(strings.Join(lines, "\n") + "\n")
+	} else {
+		var err error
+		if b, err = marshalJSON(days); err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, b, 0644), "error writing heatmap %s", path)
+}
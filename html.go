@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type htmlContributor struct {
+	Name      string
+	URL       string
+	AvatarURL string
+	Count     int
+}
+
+type htmlSection struct {
+	ID           string
+	Title        string
+	Checked      bool
+	Commits      int
+	Contributors []htmlContributor
+}
+
+type htmlBar struct {
+	Label  string
+	Count  int
+	X      int
+	Y      int
+	LabelX int
+	Width  int
+	Height int
+}
+
+type htmlReport struct {
+	GeneratedAt  string
+	Noun         string
+	Contributors int
+	Commits      int
+	Sections     []htmlSection
+	TabCSS       template.CSS
+	Bars         []htmlBar
+	ChartWidth   int
+	ChartHeight  int
+	AvatarSize   int
+}
+
+const (
+	htmlBarWidth    = 40
+	htmlBarGap      = 10
+	htmlChartHeight = 200
+	// htmlChartLabelHeight is the space left under the bars for the year.
+	htmlChartLabelHeight = 20
+)
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>External Contributors - Hall of Fame</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 0 auto; padding: 1em; }
+.card { display: inline-block; border: 1px solid #ccc; border-radius: 8px; padding: 1em 2em; margin: 0 1em 1em 0; }
+.card .value { font-size: 2em; font-weight: bold; }
+.tabs input { display: none; }
+.tabs label { display: inline-block; padding: 0.5em 1em; border: 1px solid #ccc; border-radius: 4px 4px 0 0; cursor: pointer; }
+.tabs section { display: none; border-top: 1px solid #ccc; padding-top: 1em; }
+.tabs img { vertical-align: middle; border-radius: 50%; margin-right: 0.3em; }
+{{.TabCSS}}
+svg text { font-size: 12px; text-anchor: middle; }
+</style>
+</head>
+<body>
+<h1>External Contributors - Hall of Fame</h1>
+<p>Last generated at {{.GeneratedAt}}.</p>
+<div class="card"><div class="value">{{.Contributors}}</div>contributors</div>
+<div class="card"><div class="value">{{.Commits}}</div>{{.Noun}}</div>
+<h2>{{.Noun}} per year</h2>
+<svg width="{{.ChartWidth}}" height="{{.ChartHeight}}">
+{{- range .Bars}}
+<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}" fill="#6933ff"><title>{{.Label}}: {{.Count}}</title></rect>
+<text x="{{.LabelX}}" y="{{$.ChartHeight}}">{{.Label}}</text>
+{{- end}}
+</svg>
+<div class="tabs">
+{{- range .Sections}}
+<input type="radio" name="tab" id="tab-{{.ID}}"{{if .Checked}} checked{{end}}><label for="tab-{{.ID}}">{{.Title}}</label>
+{{- end}}
+{{- range .Sections}}
+<section id="section-{{.ID}}">
+<p>{{len .Contributors}} contributors, {{.Commits}} {{$.Noun}}</p>
+<ol>
+{{- range .Contributors}}
+<li>{{if .AvatarURL}}<img src="{{.AvatarURL}}" width="{{$.AvatarSize}}" height="{{$.AvatarSize}}" alt="">{{end}}{{if .URL}}<a href="{{.URL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}} ({{.Count}})</li>
+{{- end}}
+</ol>
+</section>
+{{- end}}
+</div>
+</body>
+</html>
+`))
+
+// sizedAvatarURL returns avatarURL with the size to serve set, or "" if
+// there is no avatar, e.g. for unlinked authors.
+func sizedAvatarURL(avatarURL string, size int) string {
+	if avatarURL == "" {
+		return ""
+	}
+	u, err := url.Parse(avatarURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	// Serve at twice the displayed size for high density displays.
+	q.Set("s", strconv.Itoa(size*2))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func htmlSectionFor(id string, title string, ranked []rankedContributor) htmlSection {
+	section := htmlSection{ID: id, Title: title}
+	for _, entry := range ranked {
+		section.Commits += entry.count
+		section.Contributors = append(section.Contributors, htmlContributor{
+			Name:      entry.u.name,
+			URL:       entry.u.userURL,
+			AvatarURL: sizedAvatarURL(entry.u.avatarURL, *flagAvatarSize),
+			Count:     entry.count,
+		})
+	}
+	return section
+}
+
+// formatHTML renders a self-contained HTML dashboard, with a tab for the
+// all-time contributors and each year's, and a chart of commits per year.
+// Tabs are pure CSS so no scripts are needed.
+func formatHTML(
+	users map[string]user, start time.Time, end time.Time, generatedAt string,
+) (string, error) {
+	allTime := htmlSectionFor("all", "All-Time", rankContributors(users, start, end))
+	allTime.Checked = true
+	report := htmlReport{
+		GeneratedAt:  generatedAt,
+		Noun:         countNoun("commits"),
+		Contributors: len(allTime.Contributors),
+		Commits:      allTime.Commits,
+		Sections:     []htmlSection{allTime},
+	}
+	for _, year := range reportYears(start, end) {
+		yearFrom, yearTo := yearRange(year)
+		report.Sections = append(report.Sections, htmlSectionFor(
+			fmt.Sprintf("%d", year),
+			fmt.Sprintf("%d", year),
+			rankContributors(users, yearFrom, yearTo),
+		))
+	}
+
+	var css []string
+	for _, section := range report.Sections {
+		css = append(css, fmt.Sprintf(
+			".tabs #tab-%[1]s:checked ~ #section-%[1]s { display: block; }",
+			section.ID,
+		))
+	}
+	report.TabCSS = template.CSS(strings.Join(css, "\n"))
+
+	// The chart always runs oldest to newest, left to right, and covers the
+	// same years as the sections.
+	years := reportYears(start, end)
+	sort.Ints(years)
+	maxCommits := 0
+	for _, section := range report.Sections[1:] {
+		if section.Commits > maxCommits {
+			maxCommits = section.Commits
+		}
+	}
+	x := 0
+	for _, year := range years {
+		var commits int
+		for _, section := range report.Sections[1:] {
+			if section.ID == fmt.Sprintf("%d", year) {
+				commits = section.Commits
+			}
+		}
+		height := 0
+		if maxCommits > 0 {
+			height = commits * (htmlChartHeight - htmlChartLabelHeight) / maxCommits
+		}
+		report.Bars = append(report.Bars, htmlBar{
+			Label:  fmt.Sprintf("%d", year),
+			Count:  commits,
+			X:      x,
+			Y:      htmlChartHeight - htmlChartLabelHeight - height,
+			LabelX: x + htmlBarWidth/2,
+			Width:  htmlBarWidth,
+			Height: height,
+		})
+		x += htmlBarWidth + htmlBarGap
+	}
+	report.ChartWidth = x
+	report.ChartHeight = htmlChartHeight
+	report.AvatarSize = *flagAvatarSize
+
+	var b strings.Builder
+	if err := htmlTemplate.Execute(&b, report); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
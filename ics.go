@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var icsEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\n", `\n`,
+)
+
+// firstContribution returns the time of the user's earliest contribution.
+func firstContribution(u user) time.Time {
+	var first time.Time
+	for _, t := range u.times {
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+	}
+	return first
+}
+
+// formatICS renders an iCalendar file with a yearly recurring all-day event
+// on the anniversary of each user's first contribution.
+func formatICS(users map[string]user, generatedAt time.Time) string {
+	logins := make([]string, 0, len(users))
+	for login := range users {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//extern-contribs-agg//EN",
+	}
+	for _, login := range logins {
+		u := users[login]
+		first := firstContribution(u)
+		if first.IsZero() {
+			continue
+		}
+		first = first.UTC()
+		lines = append(
+			lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:first-contribution-%s@extern-contribs-agg", icsEscaper.Replace(login)),
+			"DTSTAMP:"+generatedAt.UTC().Format("20060102T150405Z"),
+			"DTSTART;VALUE=DATE:"+first.Format("20060102"),
+			"DTEND;VALUE=DATE:"+first.AddDate(0, 0, 1).Format("20060102"),
+			"RRULE:FREQ=YEARLY",
+			"SUMMARY:"+icsEscaper.Replace("First contribution by "+u.name),
+		)
+		if u.userURL != "" {
+			lines = append(lines, "URL:"+u.userURL)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
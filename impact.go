@@ -0,0 +1,30 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Values for -rank_by.
+const (
+	rankByCount  = "count"
+	rankByImpact = "impact"
+)
+
+// impactScore weights each of the user's contributions between from and
+// to by its recency, halving for every -impact_half_life it was made
+// before to:
+//
+//	score = sum(0.5 ^ ((to - t) / half_life))
+//
+// So a contribution made at to scores 1, and one made a half-life earlier
+// scores 0.5.
+func impactScore(u user, from time.Time, to time.Time) float64 {
+	var score float64
+	for _, t := range u.times {
+		if t.After(from) && t.Before(to) {
+			score += math.Pow(0.5, float64(to.Sub(t))/float64(*flagImpactHalfLife))
+		}
+	}
+	return score
+}
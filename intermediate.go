@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// intermediateVersion is the version of the intermediate file format
+// written by this version of the tool. Version 1 files are a bare object
+// mapping logins to RFC3339 timestamps; from version 2 that object is
+// wrapped in an intermediateFile.
+const intermediateVersion = 2
+
+type intermediateFile struct {
+	Version int                 `json:"version"`
+	Data    map[string][]string `json:"data"`
+	// Emails maps logins to the author email of their latest commit. It is
+	// optional, so files without it remain version 2.
+	Emails map[string]string `json:"emails,omitempty"`
+	// Profiles caches the looked up GitHub profile of each login, allowing
+	// output to be rendered without making any requests. Also optional.
+	Profiles map[string]intermediateProfile `json:"profiles,omitempty"`
+	// Commits lists the commits counted for each login, per
+	// -include_commit_links. Also optional.
+	Commits map[string][]intermediateCommit `json:"commits,omitempty"`
+	// Alumni lists the org members and AUTHORS entries whose commits were
+	// counted per -annotate_alumni. Also optional.
+	Alumni []string `json:"alumni,omitempty"`
+}
+
+type intermediateCommit struct {
+	Repo string    `json:"repo"`
+	SHA  string    `json:"sha"`
+	Time time.Time `json:"time"`
+}
+
+type intermediateProfile struct {
+	Name      string `json:"name"`
+	URL       string `json:"url,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	Company   string `json:"company,omitempty"`
+	Location  string `json:"location,omitempty"`
+	// InAuthors is set if the name matches a name in the AUTHORS files.
+	InAuthors bool `json:"in_authors,omitempty"`
+	// MemberLogin is set if the name matches the login of an organization
+	// member.
+	MemberLogin bool `json:"member_login,omitempty"`
+}
+
+// parseIntermediate parses an intermediate file of any version up to
+// intermediateVersion, upgrading older formats.
+func parseIntermediate(b []byte) (intermediateFile, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return intermediateFile{}, err
+	}
+	var version int
+	if v, ok := raw["version"]; !ok || json.Unmarshal(v, &version) != nil {
+		// Version 1 files have no numeric "version" key; a login named
+		// "version" would map to a list.
+		f := intermediateFile{Version: 1}
+		if err := json.Unmarshal(b, &f.Data); err != nil {
+			return intermediateFile{}, err
+		}
+		return f, nil
+	}
+	if version > intermediateVersion {
+		return intermediateFile{}, errors.Newf(
+			"intermediate file version %d is newer than the latest supported version %d",
+			version,
+			intermediateVersion,
+		)
+	}
+	var f intermediateFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return intermediateFile{}, err
+	}
+	return f, nil
+}
+
+// readTimesFile reads a file mapping logins to RFC3339 timestamps, as
+// written by writeTimesFile.
+func readTimesFile(path string) (map[string][]time.Time, error) {
+	read, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseIntermediate(read)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", path)
+	}
+	userTimes := make(map[string][]time.Time, len(f.Data))
+	for login, timesIn := range f.Data {
+		times := []time.Time{}
+		for _, tIn := range timesIn {
+			t, err := time.Parse(time.RFC3339, tIn)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid timestamp for %s in %s", login, path)
+			}
+			times = append(times, t)
+		}
+		userTimes[login] = times
+	}
+	return userTimes, nil
+}
+
+// readIntermediate reads and parses the intermediate file at path.
+func readIntermediate(path string) (intermediateFile, error) {
+	read, err := ioutil.ReadFile(path)
+	if err != nil {
+		return intermediateFile{}, err
+	}
+	f, err := parseIntermediate(read)
+	if err != nil {
+		return intermediateFile{}, errors.Wrapf(err, "error parsing %s", path)
+	}
+	return f, nil
+}
+
+// readEmails reads the author emails recorded in a file written by
+// writeIntermediate, if any.
+func readEmails(path string) (map[string]string, error) {
+	f, err := readIntermediate(path)
+	return f.Emails, err
+}
+
+// writeTimesFile writes a file mapping logins to RFC3339 timestamps, to be
+// read by readTimesFile.
+func writeTimesFile(path string, userTimes map[string][]time.Time) error {
+	return writeIntermediate(path, userTimes, intermediateFile{})
+}
+
+// writeIntermediate writes a file mapping logins to RFC3339 timestamps
+// along with the optional fields set in meta, e.g. author emails. The output
+// is deterministic for the same input so the file diffs cleanly when
+// committed.
+func writeIntermediate(
+	path string, userTimes map[string][]time.Time, meta intermediateFile,
+) error {
+	f := meta
+	f.Version = intermediateVersion
+	f.Data = map[string][]string{}
+	for _, commits := range f.Commits {
+		sort.Slice(commits, func(i, j int) bool {
+			if commits[i].Time.Equal(commits[j].Time) {
+				return commits[i].SHA < commits[j].SHA
+			}
+			return commits[i].Time.Before(commits[j].Time)
+		})
+	}
+	for user, times := range userTimes {
+		// Times are sorted so the file does not change with scan order.
+		sorted := append([]time.Time(nil), times...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+		for _, t := range sorted {
+			f.Data[user] = append(f.Data[user], t.Format(time.RFC3339))
+		}
+	}
+	b, err := marshalJSON(f)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, b, 0644), "error writing %s", path)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIntermediateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "intermediate.json")
+	userTimes := map[string][]time.Time{
+		"alice": {
+			time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC),
+			time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC),
+		},
+		"bob": {time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	if err := writeTimesFile(path, userTimes); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readTimesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(userTimes) {
+		t.Fatalf("expected %d users, got %d", len(userTimes), len(got))
+	}
+	for login, times := range userTimes {
+		if len(got[login]) != len(times) {
+			t.Fatalf("expected %d times for %s, got %v", len(times), login, got[login])
+		}
+		for i := range times {
+			if !got[login][i].Equal(times[i]) {
+				t.Errorf("expected time %d of %s to be %s, got %s", i, login, times[i], got[login][i])
+			}
+		}
+	}
+}
+
+func TestReadTimesFileMalformedTimestamp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "intermediate.json")
+	if err := ioutil.WriteFile(path, []byte(`{"version":2,"data":{"alice":["yesterday"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("expected an error rather than a panic, got %v", r)
+		}
+	}()
+	if _, err := readTimesFile(path); err == nil {
+		t.Fatal("expected an error for a malformed timestamp")
+	}
+}
+
+func TestParseIntermediateVersions(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		in              string
+		expectedVersion int
+		expectedErr     bool
+	}{
+		{
+			name:            "legacy",
+			in:              `{"alice":["2020-03-01T10:00:00Z"]}`,
+			expectedVersion: 1,
+		},
+		{
+			// A login named "version" maps to a list, not a number.
+			name:            "legacy with a version login",
+			in:              `{"version":["2020-03-01T10:00:00Z"],"alice":["2020-03-01T10:00:00Z"]}`,
+			expectedVersion: 1,
+		},
+		{
+			name:            "v2",
+			in:              `{"version":2,"data":{"alice":["2020-03-01T10:00:00Z"]}}`,
+			expectedVersion: 2,
+		},
+		{
+			name:        "newer",
+			in:          `{"version":3,"data":{}}`,
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := parseIntermediate([]byte(tc.in))
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Version != tc.expectedVersion {
+				t.Errorf("expected version %d, got %d", tc.expectedVersion, f.Version)
+			}
+			if got := f.Data["alice"]; len(got) != 1 || got[0] != "2020-03-01T10:00:00Z" {
+				t.Errorf("expected alice's timestamp, got %v", got)
+			}
+		})
+	}
+}
+
+func TestWriteTimesFileStable(t *testing.T) {
+	dir := t.TempDir()
+	a := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	b := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	c := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	// The same data in different scan orders.
+	inputs := []map[string][]time.Time{
+		{"alice": {a, b}, "bob": {c}},
+		{"bob": {c}, "alice": {b, a}},
+	}
+	var first []byte
+	for i, userTimes := range inputs {
+		path := filepath.Join(dir, "intermediate.json")
+		if err := writeTimesFile(path, userTimes); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = got
+		} else if string(got) != string(first) {
+			t.Errorf("expected identical output, got:\n%s\nthen:\n%s", first, got)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// readListFile reads a file listing one entry, e.g. a login, per line.
+// Blank lines and lines starting with "#" are ignored.
+func readListFile(path string) (map[string]struct{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", path)
+	}
+	entries := map[string]struct{}{}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries[line] = struct{}{}
+	}
+	return entries, nil
+}
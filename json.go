@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// marshalJSON marshals v, indented if -pretty is set. Map keys are always
+// sorted by encoding/json, so output is stable either way.
+func marshalJSON(v interface{}) ([]byte, error) {
+	if *flagPretty {
+		return json.MarshalIndent(v, "", "  ")
+	}
+	return json.Marshal(v)
+}
+
+type jsonContributor struct {
+	Login  string `json:"login"`
+	Name   string `json:"name"`
+	URL    string `json:"url,omitempty"`
+	Alumni bool   `json:"alumni,omitempty"`
+	// Email is the email or email domain shown, per -show_email_domain
+	// and -show_full_email.
+	Email string `json:"email,omitempty"`
+	// Company is the company on the contributor's profile, per
+	// -show_company.
+	Company string `json:"company,omitempty"`
+	// Location is the location on the contributor's profile, per
+	// -show_location.
+	Location string   `json:"location,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+	// Source is "github", "manual" or "mixed", per contributionSource.
+	Source  string `json:"source"`
+	Commits int    `json:"commits"`
+	// MergedPRs, Reviews and DiscussionPosts are the number of merged pull
+	// requests, reviews and discussion posts made, if counted per
+	// -count_merged_prs, -count_reviews and -count_discussions.
+	MergedPRs       *int `json:"merged_prs,omitempty"`
+	Reviews         *int `json:"reviews,omitempty"`
+	DiscussionPosts *int `json:"discussion_posts,omitempty"`
+	// ByYear maps each year to the number of commits made in it, counted
+	// the same way as Commits per -count_mode.
+	ByYear map[int]int `json:"by_year"`
+	// SpanDays is the number of days between the first and last commit.
+	SpanDays int `json:"span_days"`
+	// CommitLinks lists the commits counted, per -include_commit_links.
+	CommitLinks []jsonCommit `json:"commit_links,omitempty"`
+}
+
+type jsonCommit struct {
+	SHA  string    `json:"sha"`
+	URL  string    `json:"url"`
+	Time time.Time `json:"time"`
+}
+
+type jsonReport struct {
+	GeneratedAt string `json:"generated_at"`
+	// CountMode is what each contributor's commits count, per -count_mode.
+	CountMode    string            `json:"count_mode"`
+	Contributors []jsonContributor `json:"contributors"`
+}
+
+// countBetween returns the number of times between from and to.
+func countBetween(times []time.Time, from time.Time, to time.Time) int {
+	n := 0
+	for _, t := range times {
+		if t.After(from) && t.Before(to) {
+			n++
+		}
+	}
+	return n
+}
+
+// jsonContributors returns everyone with commits, reviews, discussion posts
+// or merged pull requests between start and end: the committers ranked by
+// their commits, then everyone else by login, with no commits.
+func jsonContributors(
+	users map[string]user, others []map[string]user, start time.Time, end time.Time,
+) []rankedContributor {
+	ranked := rankContributors(users, start, end)
+	seen := map[string]struct{}{}
+	for _, entry := range ranked {
+		seen[entry.u.login] = struct{}{}
+	}
+	var rest []rankedContributor
+	for _, m := range others {
+		for login, u := range m {
+			if _, ok := seen[login]; ok || countBetween(u.times, start, end) == 0 {
+				continue
+			}
+			seen[login] = struct{}{}
+			if committer, ok := users[login]; ok {
+				u = committer
+			} else {
+				u.times = nil
+			}
+			rest = append(rest, rankedContributor{u: u})
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		return rest[i].u.login < rest[j].u.login
+	})
+	return append(ranked, rest...)
+}
+
+// formatJSON renders the contributors as JSON, breaking down each one's
+// contributions by type. mergers, reviewers and discussers are nil unless
+// being counted.
+func formatJSON(
+	users map[string]user,
+	reviewers map[string]user,
+	discussers map[string]user,
+	mergers map[string]user,
+	start time.Time,
+	end time.Time,
+	generatedAt string,
+) (string, error) {
+	report := jsonReport{
+		GeneratedAt:  generatedAt,
+		CountMode:    *flagCountMode,
+		Contributors: []jsonContributor{},
+	}
+	others := []map[string]user{mergers, reviewers, discussers}
+	for _, entry := range jsonContributors(users, others, start, end) {
+		c := jsonContributor{
+			Login:    entry.u.login,
+			Name:     entry.u.name,
+			URL:      entry.u.userURL,
+			Alumni:   entry.u.alumni,
+			Email:    displayEmail(entry.u.email),
+			Company:  displayCompany(entry.u.company),
+			Location: displayLocation(entry.u.location),
+			Labels:   entry.u.labels,
+			Source:   contributionSource(entry.u),
+			Commits:  entry.count,
+			ByYear:   map[int]int{},
+			SpanDays: int(contributionSpan(entry.u).Hours() / 24),
+		}
+		if mergers != nil {
+			merged := countBetween(mergers[entry.u.login].times, start, end)
+			c.MergedPRs = &merged
+		}
+		if reviewers != nil {
+			reviews := countBetween(reviewers[entry.u.login].times, start, end)
+			c.Reviews = &reviews
+		}
+		if discussers != nil {
+			posts := countBetween(discussers[entry.u.login].times, start, end)
+			c.DiscussionPosts = &posts
+		}
+		for _, t := range countedTimes(entry.u.times, start, end) {
+			c.ByYear[t.Year()]++
+		}
+		for _, commit := range entry.u.commits {
+			if commit.Time.After(start) && commit.Time.Before(end) {
+				c.CommitLinks = append(c.CommitLinks, jsonCommit{
+					SHA: commit.SHA,
+					URL: fmt.Sprintf(
+						"%s/%s/commit/%s",
+						strings.TrimSuffix(*flagGithubBaseURL, "/"),
+						commit.Repo,
+						commit.SHA,
+					),
+					Time: commit.Time,
+				})
+			}
+		}
+		report.Contributors = append(report.Contributors, c)
+	}
+	b, err := marshalJSON(report)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+type jsonlRecord struct {
+	Login string `json:"login"`
+	Name  string `json:"name"`
+	Year  int    `json:"year"`
+	Count int    `json:"count"`
+}
+
+// formatJSONL renders a JSON record per contributor per year they
+// contributed in, one per line, ranked within each year, oldest year first
+// and restricted to -output_years. -pretty is ignored so every record stays
+// on a single line.
+func formatJSONL(users map[string]user, start time.Time, end time.Time) (string, error) {
+	years := reportYears(start, end)
+	sort.Ints(years)
+	var b strings.Builder
+	for _, year := range years {
+		yearFrom, yearTo := yearRange(year)
+		for _, entry := range rankContributors(users, yearFrom, yearTo) {
+			line, err := json.Marshal(jsonlRecord{
+				Login: entry.u.login,
+				Name:  entry.u.name,
+				Year:  year,
+				Count: entry.count,
+			})
+			if err != nil {
+				return "", err
+			}
+			b.Write(line)
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
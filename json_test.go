@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatJSONBreakdown(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC)
+	when := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	reviewers := map[string]user{
+		"alice":    {login: "alice", name: "Alice", times: []time.Time{when}},
+		"reviewer": {login: "reviewer", name: "Reviewer", times: []time.Time{when, when}},
+	}
+	discussers := map[string]user{
+		"poster": {login: "poster", name: "Poster", times: []time.Time{when}},
+	}
+	mergers := map[string]user{
+		"alice": {login: "alice", name: "Alice", times: []time.Time{when}},
+	}
+	out, err := formatJSON(testUsers(), reviewers, discussers, mergers, start, end, "GENERATED_AT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	type breakdown struct {
+		commits, mergedPRs, reviews, posts int
+	}
+	got := map[string]breakdown{}
+	var order []string
+	for _, c := range report.Contributors {
+		order = append(order, c.Login)
+		got[c.Login] = breakdown{
+			commits:   c.Commits,
+			mergedPRs: *c.MergedPRs,
+			reviews:   *c.Reviews,
+			posts:     *c.DiscussionPosts,
+		}
+	}
+	// Contributors without commits follow the committers, by login.
+	expectedOrder := "alice,bob,carol,poster,reviewer"
+	if got := strings.Join(order, ","); got != expectedOrder {
+		t.Errorf("expected contributors %s, got %s", expectedOrder, got)
+	}
+	for login, expected := range map[string]breakdown{
+		"alice":    {commits: 3, mergedPRs: 1, reviews: 1},
+		"bob":      {commits: 2},
+		"reviewer": {reviews: 2},
+		"poster":   {posts: 1},
+	} {
+		if got[login] != expected {
+			t.Errorf("expected %s to have %+v, got %+v", login, expected, got[login])
+		}
+	}
+}
+
+func TestFormatJSONByYearActiveDays(t *testing.T) {
+	*flagCountMode = countModeActiveDays
+	defer func() { *flagCountMode = countModeCommits }()
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC)
+	users := map[string]user{
+		"alice": {login: "alice", name: "Alice", times: []time.Time{
+			time.Date(2019, 3, 1, 9, 0, 0, 0, time.UTC),
+			time.Date(2019, 3, 1, 17, 0, 0, 0, time.UTC),
+			time.Date(2020, 5, 2, 9, 0, 0, 0, time.UTC),
+			time.Date(2020, 5, 3, 9, 0, 0, 0, time.UTC),
+		}},
+	}
+	out, err := formatJSON(users, nil, nil, nil, start, end, "GENERATED_AT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	c := report.Contributors[0]
+	if c.Commits != 3 || c.ByYear[2019] != 1 || c.ByYear[2020] != 2 {
+		t.Errorf("expected 3 active days, 1 in 2019 and 2 in 2020; got %d, by year %v", c.Commits, c.ByYear)
+	}
+}
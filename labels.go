@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// readLabels reads a JSON file mapping logins to labels, e.g.
+//
+//	{"somelogin": ["student", "maintainer of somelib"]}
+func readLabels(path string) (map[string][]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var labels map[string][]string
+	if err := json.Unmarshal(b, &labels); err != nil {
+		return nil, errors.Wrapf(err, "error parsing labels %s", path)
+	}
+	return labels, nil
+}
+
+// applyLabels sets the labels of any user listed in labels.
+func applyLabels(users map[string]user, labels map[string][]string) {
+	for login, l := range labels {
+		u, ok := users[login]
+		if !ok {
+			continue
+		}
+		u.labels = l
+		users[login] = u
+	}
+}
+
+// formatLabels renders a Markdown section per label, alphabetically,
+// ranking the contributors with that label.
+func formatLabels(users map[string]user, start time.Time, end time.Time) string {
+	byLabel := map[string]map[string]user{}
+	for login, u := range users {
+		for _, label := range u.labels {
+			if byLabel[label] == nil {
+				byLabel[label] = map[string]user{}
+			}
+			byLabel[label][login] = u
+		}
+	}
+	labels := make([]string, 0, len(byLabel))
+	for label := range byLabel {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	var out string
+	for _, label := range labels {
+		out += fmt.Sprintf(
+			`### %s
+
+%s
+
+`,
+			escapeMarkdown(label),
+			formatContributors(rankContributors(byLabel[label], start, end), countNoun("commits")),
+		)
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+)
+
+// adaptiveLimiter bounds the number of concurrent requests, adjusting the
+// bound according to the remaining rate limit budget: with plenty of budget
+// left before the reset, more requests run concurrently, and as it runs
+// low, fewer do.
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	inFlight int
+	limit    int
+	min      int
+	max      int
+}
+
+func newAdaptiveLimiter(min int, max int) *adaptiveLimiter {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	l := &adaptiveLimiter{limit: max, min: min, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until another request may be made.
+func (l *adaptiveLimiter) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+}
+
+// release marks a request as done, adjusting the limit based on the rate
+// observed in its response, if any.
+func (l *adaptiveLimiter) release(resp *github.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if resp != nil && !resp.Rate.Reset.Time.IsZero() {
+		// Allow one request in flight for every request per second we can
+		// afford to make until the reset.
+		secondsToReset := time.Until(resp.Rate.Reset.Time).Seconds()
+		if secondsToReset < 1 {
+			secondsToReset = 1
+		}
+		limit := int(float64(resp.Rate.Remaining) / secondsToReset)
+		if limit < l.min {
+			limit = l.min
+		}
+		if limit > l.max {
+			limit = l.max
+		}
+		l.limit = limit
+	}
+	l.cond.Broadcast()
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strconv"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// formatCount formats n for -locale, e.g. with thousands separators. With
+// no locale, n is formatted plainly. Only counts are localized: x/text has
+// no date formatting, so dates stay in ISO 8601 and month and weekday names
+// in English whatever the locale.
+func formatCount(n int) string {
+	if *flagLocale == "" {
+		return strconv.Itoa(n)
+	}
+	return message.NewPrinter(language.Make(*flagLocale)).Sprintf("%d", n)
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestFormatCount(t *testing.T) {
+	defer func() { *flagLocale = "" }()
+	for _, tc := range []struct {
+		locale   string
+		n        int
+		expected string
+	}{
+		{locale: "", n: 1234567, expected: "1234567"},
+		{locale: "en", n: 1234567, expected: "1,234,567"},
+		{locale: "de", n: 1234567, expected: "1.234.567"},
+		{locale: "de", n: 12, expected: "12"},
+	} {
+		*flagLocale = tc.locale
+		if got := formatCount(tc.n); got != tc.expected {
+			t.Errorf("formatCount(%d) with locale %q = %q, expected %q", tc.n, tc.locale, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const unknownRegion = "Unknown"
+
+// locationRegion makes a best effort guess at the region of a free text
+// profile location, taking the last comma separated part, e.g. "Germany"
+// for "Berlin, Germany". Locations without any letters are unknown.
+func locationRegion(location string) string {
+	parts := strings.Split(location, ",")
+	region := strings.TrimSpace(parts[len(parts)-1])
+	if strings.IndexFunc(region, unicode.IsLetter) < 0 {
+		return unknownRegion
+	}
+	return region
+}
+
+// displayLocation returns the location shown for a contributor, which is
+// nothing without -show_location.
+func displayLocation(location string) string {
+	if !*flagShowLocation {
+		return ""
+	}
+	return strings.TrimSpace(location)
+}
+
+// formatRegions lists the number of contributors between start and end,
+// and their contributions, by locationRegion, most contributors first.
+func formatRegions(users map[string]user, start time.Time, end time.Time) string {
+	type region struct {
+		name         string
+		contributors int
+		count        int
+	}
+	byName := map[string]*region{}
+	for _, entry := range rankContributors(users, start, end) {
+		name := locationRegion(entry.u.location)
+		r, ok := byName[strings.ToLower(name)]
+		if !ok {
+			r = &region{name: name}
+			byName[strings.ToLower(name)] = r
+		}
+		r.contributors++
+		r.count += entry.count
+	}
+	regions := make([]*region, 0, len(byName))
+	for _, r := range byName {
+		regions = append(regions, r)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].contributors == regions[j].contributors {
+			return regions[i].name < regions[j].name
+		}
+		return regions[i].contributors > regions[j].contributors
+	})
+	var lines []string
+	for _, r := range regions {
+		lines = append(lines, fmt.Sprintf(
+			"* %s: %s contributors, %s %s",
+			escapeMarkdown(r.name),
+			formatCount(r.contributors),
+			formatCount(r.count),
+			countNoun("commits"),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
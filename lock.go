@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// lockPollInterval is how often a held lock is retried while waiting.
+const lockPollInterval = time.Second
+
+// acquireLock takes an exclusive advisory lock on the file at path,
+// recording our PID in it. If another process holds the lock, it is
+// retried for up to wait before giving up. The lock is released by
+// calling the returned function, or by the OS when the process exits for
+// any reason, including being killed by a signal.
+func acquireLock(path string, wait time.Duration) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening lock file %s", path)
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, errors.Wrapf(err, "error locking %s", path)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			holder, _ := ioutil.ReadFile(path)
+			return nil, errors.Newf(
+				"another run (pid %s) holds the lock %s",
+				string(holder),
+				path,
+			)
+		}
+		fmt.Printf("* Waiting for lock %s\n", path)
+		time.Sleep(lockPollInterval)
+	}
+	if err := f.Truncate(0); err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "error writing lock file %s", path)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "error writing lock file %s", path)
+	}
+	return func() {
+		_ = f.Truncate(0)
+		// Closing the file releases the lock.
+		_ = f.Close()
+	}, nil
+}
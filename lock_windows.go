@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	"github.com/cockroachdb/errors"
+)
+
+func tryLock(f *os.File) (bool, error) {
+	return false, errors.New("-parallel_safe is not supported on Windows")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// mailmapEntry is a line of a .mailmap file, mapping commits by
+// commitEmail, and commitName if set, to properName and properEmail,
+// whichever are set.
+type mailmapEntry struct {
+	properName  string
+	properEmail string
+	commitName  string
+	commitEmail string
+}
+
+// mailmap canonicalizes commit author identities as git does, per
+// gitmailmap(5).
+type mailmap struct {
+	entries []mailmapEntry
+}
+
+// parseMailmap parses the contents of a .mailmap file, skipping lines it
+// does not understand.
+func parseMailmap(contents string) *mailmap {
+	m := &mailmap{}
+	for _, line := range strings.Split(contents, "\n") {
+		// Comments may follow an entry, but never appear inside an email.
+		if i := strings.LastIndex(line, ">"); i >= 0 {
+			if j := strings.Index(line[i:], "#"); j >= 0 {
+				line = line[:i+j]
+			}
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		var names, emails []string
+		rest := line
+		for {
+			open := strings.Index(rest, "<")
+			if open < 0 {
+				break
+			}
+			end := strings.Index(rest[open:], ">")
+			if end < 0 {
+				break
+			}
+			names = append(names, strings.TrimSpace(rest[:open]))
+			emails = append(emails, strings.TrimSpace(rest[open+1:open+end]))
+			rest = rest[open+end+1:]
+		}
+		switch len(emails) {
+		case 1:
+			// Proper Name <commit@email>
+			if names[0] != "" {
+				m.entries = append(m.entries, mailmapEntry{
+					properName:  names[0],
+					commitEmail: strings.ToLower(emails[0]),
+				})
+			}
+		case 2:
+			// [Proper Name] <proper@email> [Commit Name] <commit@email>
+			m.entries = append(m.entries, mailmapEntry{
+				properName:  names[0],
+				properEmail: emails[0],
+				commitName:  names[1],
+				commitEmail: strings.ToLower(emails[1]),
+			})
+		}
+	}
+	return m
+}
+
+// lookup returns the canonical name and email of a commit author. Entries
+// matching the name as well as the email take precedence.
+func (m *mailmap) lookup(name string, email string) (string, string) {
+	var match *mailmapEntry
+	for i := range m.entries {
+		e := &m.entries[i]
+		if e.commitEmail != strings.ToLower(email) {
+			continue
+		}
+		if e.commitName != "" {
+			if !strings.EqualFold(e.commitName, name) {
+				continue
+			}
+			match = e
+			break
+		}
+		if match == nil {
+			match = e
+		}
+	}
+	if match == nil {
+		return name, email
+	}
+	if match.properName != "" {
+		name = match.properName
+	}
+	if match.properEmail != "" {
+		email = match.properEmail
+	}
+	return name, email
+}
+
+// mailmapFor returns the .mailmap of the given repo, which is empty if the
+// repo has none. Each repo's is fetched at most once per scan, barring
+// races between repos scanned in parallel.
+func (s *scanner) mailmapFor(
+	ctx context.Context, ghClient *github.Client, repo string,
+) (*mailmap, error) {
+	s.mu.Lock()
+	m, ok := s.mailmaps[repo]
+	s.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+	file, _, resp, err := ghClient.Repositories.GetContents(ctx, s.org, repo, ".mailmap", nil)
+	if err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return nil, errors.Wrapf(markGithubError(err), "error getting .mailmap of %s", repo)
+		}
+		m = &mailmap{}
+	} else {
+		contents, err := file.GetContent()
+		if err != nil {
+			return nil, errors.Wrapf(err, "error decoding .mailmap of %s", repo)
+		}
+		m = parseMailmap(contents)
+	}
+	s.mu.Lock()
+	s.mailmaps[repo] = m
+	s.mu.Unlock()
+	return m, nil
+}
+
+// applyMailmap returns the commit with its git author canonicalized by the
+// repo's .mailmap.
+func (s *scanner) applyMailmap(
+	ctx context.Context, ghClient *github.Client, repo string, commit *github.RepositoryCommit,
+) (*github.RepositoryCommit, error) {
+	m, err := s.mailmapFor(ctx, ghClient, repo)
+	if err != nil {
+		return nil, err
+	}
+	author := commit.GetCommit().GetAuthor()
+	name, email := m.lookup(author.GetName(), author.GetEmail())
+	if name == author.GetName() && email == author.GetEmail() {
+		return commit, nil
+	}
+	mapped := *commit
+	gitCommit := *commit.GetCommit()
+	gitCommit.Author = &github.CommitAuthor{Date: author.Date, Name: &name, Email: &email}
+	mapped.Commit = &gitCommit
+	return &mapped, nil
+}
@@ -2,11 +2,11 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"net/http"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -14,6 +14,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/google/go-github/v30/github"
+	"golang.org/x/text/language"
 )
 
 var flagOrganization = flag.String(
@@ -34,13 +35,23 @@ var flagAuthorsRepo = flag.String(
 var flagAuthorsPath = flag.String(
 	"authors_path",
 	"AUTHORS",
-	"source of authors files",
+	"source of authors files, comma separated",
 )
 var flagRepos = flag.String(
 	"repos",
 	"cockroach,pebble,docs,activerecord-cockroachdb-adapter,cockroach-go,cockroach-operator,django-cockroachdb,sequelize-cockroachdb,sqlalchemy-cockroachdb",
 	"repos to lookup, comma separated",
 )
+var flagReposFromOrg = flag.Bool(
+	"repos_from_org",
+	false,
+	"if true, looks at every public repo in -organization which is neither archived nor a fork, instead of -repos",
+)
+var flagExcludeRepos = flag.String(
+	"exclude_repos",
+	"",
+	"repos not to look at, comma separated",
+)
 var flagIntermediateOutput = flag.String(
 	"intermediate_output_file",
 	"intermediate_output.json",
@@ -51,15 +62,75 @@ var flagOutput = flag.String(
 	"output.md",
 	"output file",
 )
+var flagFormat = flag.String(
+	"format",
+	"markdown",
+	"output format: markdown, json, jsonl with a line per contributor per year, html for a self-contained dashboard, ics for a calendar of first contribution anniversaries, or social for a post thanking contributors by @handle",
+)
+var flagOutputs = flag.String(
+	"outputs",
+	"",
+	"if set, comma separated output files to write instead of -output, each in the format given by its extension: .md, .json, .jsonl, .html or .ics",
+)
+var flagHeatmapFile = flag.String(
+	"heatmap_file",
+	"",
+	"if set, also writes the number of contributions on each UTC day to this file for a calendar heatmap, as CSV if it ends in .csv and JSON otherwise",
+)
+var flagProgressJSON = flag.String(
+	"progress_json",
+	"",
+	"if set, emits progress events as newline delimited JSON to this file or named pipe, or to stderr if \"-\"",
+)
+var flagSocialMaxChars = flag.Int(
+	"social_max_chars",
+	280,
+	"with -format=social, the most characters the post may have",
+)
+var flagPretty = flag.Bool(
+	"pretty",
+	false,
+	"if true, indents JSON output and intermediate output files",
+)
+var flagGithubBaseURL = flag.String(
+	"github_base_url",
+	defaultGithubBaseURL,
+	"base URL of the GitHub instance; for GitHub Enterprise Server, the API is used at <base>/api/v3/",
+)
+var flagNoRepoLinks = flag.Bool(
+	"no_repo_links",
+	false,
+	"if true, omits the line linking the scanned repos from the output",
+)
 var flagUseIntermediate = flag.Bool(
 	"use_intermediate",
 	false,
 	"if true, generates output from pre-generated intermediate output file",
 )
+var flagRenderOnly = flag.Bool(
+	"render_only",
+	false,
+	"with -use_intermediate, renders the output using the profiles cached in -intermediate_output_file without making any requests",
+)
+var flagCompareOrgs = flag.String(
+	"compare_orgs",
+	"",
+	"if set, scans each of these comma separated organizations and outputs a table comparing their external contributors by year; -repos must then be given as org/repo",
+)
+var flagMergeIntermediates = flag.String(
+	"merge_intermediates",
+	"",
+	"if set, merges these comma separated intermediate output files into -intermediate_output_file and exits",
+)
 var flagBlocklist = flag.String(
 	"blocklist",
 	"petermattis-square,chriscasano,craig[bot],nigeltao,dependabot,dependabot[bot],alimi,timgraham,papb,chrislovecnm,marlabrizel,rkruze,alan-mas,ajwerner,alinadonisa,douglasselias,kannanlakshmi,mnovelodou,JuanLeon1,keithdoggett,dougmrqs,rainleander,mgoddard",
-	"comma separated list of people to exclude",
+	"comma separated list of people to exclude; \"*\" matches any characters, e.g. \"*[bot]\"",
+)
+var flagAllowlist = flag.String(
+	"allowlist",
+	"",
+	"comma separated list of people to always include, even if they would otherwise be excluded; \"*\" matches any characters",
 )
 var flagStartDate = flag.String(
 	"start_date",
@@ -71,6 +142,426 @@ var flagEndDate = flag.String(
 	"",
 	"YYYY-MM-DD date of when to end - defaults to now",
 )
+var flagSQLiteFile = flag.String(
+	"sqlite_file",
+	"",
+	"if set, writes the scanned contributors and contributions to this SQLite database",
+)
+var flagSearchQuery = flag.String(
+	"search_query",
+	"",
+	"if set, also finds commits using this GitHub commit search query (e.g. \"author:somelogin\"), scoped to -organization unless org: or repo: is given",
+)
+var flagVerboseExclude = flag.Bool(
+	"verbose_exclude",
+	false,
+	"if true, prints how many commits each filter excluded after scanning",
+)
+var flagReportBlocklistHits = flag.Bool(
+	"report_blocklist_hits",
+	false,
+	"if true, prints how many commits each blocklist entry excluded after scanning, to find entries which no longer match anyone",
+)
+var flagPreview = flag.Bool(
+	"preview",
+	false,
+	"if true, prints the top contributors by login after scanning and exits without looking up users or writing output",
+)
+var flagSkipUnchanged = flag.Bool(
+	"skip_unchanged",
+	false,
+	"if true, skips writing the output if its content (ignoring the generation time) is unchanged since the last run",
+)
+var flagRequireMergedPR = flag.Bool(
+	"require_merged_pr",
+	false,
+	"if true, only counts contributors with at least one merged pull request in -organization; makes a search request per contributor",
+)
+var flagUseMailmap = flag.Bool(
+	"use_mailmap",
+	false,
+	"if true, canonicalizes commit author names and emails with each repo's .mailmap before filtering and counting, as git shortlog does",
+)
+var flagResolveSquashAuthors = flag.Bool(
+	"resolve_squash_authors",
+	false,
+	"if true, credits commits squash merged from a pull request, i.e. titled \"... (#1234)\", to the pull request's author; makes a request per such pull request",
+)
+var flagResolveUnlinked = flag.Bool(
+	"resolve_unlinked",
+	false,
+	"if true, counts commits whose author email is not linked to a GitHub account, resolving the account by email where possible",
+)
+var flagCountCoauthors = flag.Bool(
+	"count_coauthors",
+	false,
+	"if true, also credits the co-authors named in Co-authored-by trailers; co-authors without a GitHub noreply email are only counted with -resolve_unlinked",
+)
+var flagExcludeCoauthoredInternal = flag.Bool(
+	"exclude_coauthored_internal",
+	false,
+	"if true, with -count_coauthors, applies the same organization email, AUTHORS, member and blocklist filters to co-authors as to commit authors",
+)
+var flagInternalLoginsFile = flag.String(
+	"internal_logins_file",
+	"",
+	"if set, always excludes the logins listed in this file, one per line, even if allowlisted; for internal contributors the other filters miss",
+)
+var flagExcludeEmails = flag.String(
+	"exclude_emails",
+	"",
+	"comma separated list of commit author emails to exclude, matched case insensitively",
+)
+var flagExcludeEmailsFile = flag.String(
+	"exclude_emails_file",
+	"",
+	"if set, also excludes the commit author emails listed in this file, one per line",
+)
+var flagNoEmailFilter = flag.Bool(
+	"no_email_filter",
+	false,
+	"if true, does not exclude commits authored with a cockroachlabs.com email",
+)
+var flagNoAuthorsFilter = flag.Bool(
+	"no_authors_filter",
+	false,
+	"if true, does not exclude commits matching a name or email in the AUTHORS files",
+)
+var flagParallelSafe = flag.Bool(
+	"parallel_safe",
+	false,
+	"if true, holds a lock on -lock_file while running so overlapping runs cannot clobber each other's output",
+)
+var flagLockFile = flag.String(
+	"lock_file",
+	"extern-contribs-agg.lock",
+	"lock file used by -parallel_safe",
+)
+var flagLockWait = flag.Duration(
+	"lock_wait",
+	0,
+	"with -parallel_safe, how long to wait for another run to release the lock before failing; by default fails immediately",
+)
+var flagManifest = flag.String(
+	"manifest",
+	"",
+	"if set, writes a JSON manifest of the inputs and output checksums to this file",
+)
+var flagCheckpointFile = flag.String(
+	"checkpoint_file",
+	"",
+	"if set, records scan progress to this file after every page so an interrupted scan can resume; removed once the scan completes",
+)
+var flagAttemptedContributors = flag.Bool(
+	"attempted_contributors",
+	false,
+	"if true, also lists external authors of pull requests which were never merged in a separate section",
+)
+var flagAttemptedIntermediateOutput = flag.String(
+	"attempted_intermediate_output_file",
+	"attempted_intermediate_output.json",
+	"place where intermediate output of unmerged pull request authors is placed",
+)
+var flagCountMergedPRs = flag.Bool(
+	"count_merged_prs",
+	false,
+	"if true, also counts merged pull requests by external contributors, reported as merged_prs in JSON output",
+)
+var flagMergedPRsIntermediateOutput = flag.String(
+	"merged_prs_intermediate_output_file",
+	"merged_prs_intermediate_output.json",
+	"place where intermediate output of merged pull request authors is placed",
+)
+var flagTokenFile = flag.String(
+	"token_file",
+	"",
+	"if set, reads the GitHub token from this file, which must not be readable by group or others, instead of the environment",
+)
+var flagCACert = flag.String(
+	"ca_cert",
+	"",
+	"if set, a PEM file of CA certificates to trust in addition to the system roots, e.g. for a corporate proxy",
+)
+var flagUserAgent = flag.String(
+	"user_agent",
+	"extern-contribs-agg",
+	"User-Agent sent with every GitHub API request",
+)
+var flagGithubAPIVersion = flag.String(
+	"github_api_version",
+	"2022-11-28",
+	"version of the GitHub REST API to request, sent as the X-GitHub-Api-Version header; empty to use GitHub's default",
+)
+var flagLogRequests = flag.Bool(
+	"log_requests",
+	false,
+	"if true, logs every GitHub API request made",
+)
+var flagCohorts = flag.Bool(
+	"cohorts",
+	false,
+	"if true, includes a table of contributor retention grouped by year of first contribution",
+)
+var flagOverridesFile = flag.String(
+	"overrides_file",
+	"",
+	"if set, a JSON file mapping logins to a {\"name\", \"url\"} to display instead of the GitHub profile's",
+)
+var flagSinceDays = flag.Int(
+	"since_days",
+	0,
+	"if set, starts from this many days ago instead of -start_date",
+)
+var flagETagCacheFile = flag.String(
+	"etag_cache_file",
+	"",
+	"if set, caches responses with their ETags in this file and makes requests conditional on them, so unchanged pages do not count against the rate limit",
+)
+var flagListMembers = flag.Bool(
+	"list_members",
+	false,
+	"if true, prints the members of -organization and exits",
+)
+var flagLintAuthors = flag.Bool(
+	"lint_authors",
+	false,
+	"if true, prints anomalies in the AUTHORS files which degrade filtering, such as malformed lines and duplicate emails, and exits",
+)
+var flagContributorsOnly = flag.Bool(
+	"contributors_only",
+	false,
+	"if true, outputs only an alphabetical, comma separated list of contributor names",
+)
+var flagSummaryOnly = flag.Bool(
+	"summary_only",
+	false,
+	"if true, prints a single line summarizing the contributors and their contributions, without writing the report",
+)
+var flagForLogin = flag.String(
+	"for_login",
+	"",
+	"if set, outputs the contribution history of only this login; scan with -include_commit_links to include a breakdown by repo and links to each commit",
+)
+var flagExcludeBranches = flag.String(
+	"exclude_branches",
+	"",
+	"if set, comma separated branches whose commits are not counted unless also on another branch, e.g. generated deploy branches such as gh-pages; only default branches are scanned, so this only affects repos whose default branch is listed, listing their other branches' commits to check membership, and skips forks whose default branch is listed",
+)
+var flagNewSince = flag.String(
+	"new_since",
+	"",
+	"if set, only outputs contributors whose first contribution was on or after this date (YYYY-MM-DD), however much they contributed since",
+)
+var flagOnBudgetExceeded = flag.String(
+	"on_budget_exceeded",
+	"",
+	"if set, estimates the requests the scan makes beforehand and, if they exceed the remaining rate limit, either warns, aborts or waits for the limit to reset: warn, abort or wait",
+)
+var flagLogins = flag.String(
+	"logins",
+	"",
+	"if set, comma separated logins whose commits anywhere in -organization are searched for instead of scanning -repos, e.g. to report on a cohort",
+)
+var flagParallelRepos = flag.Int(
+	"parallel_repos",
+	1,
+	"number of repos to scan in parallel",
+)
+var flagFailFast = flag.Bool(
+	"fail_fast",
+	true,
+	"if true, the first repo which fails to scan cancels the others; if false, every repo is scanned and all failures reported",
+)
+var flagMinConcurrency = flag.Int(
+	"min_concurrency",
+	1,
+	"minimum number of concurrent user lookups when the rate limit budget is low",
+)
+var flagMaxConcurrency = flag.Int(
+	"max_concurrency",
+	20,
+	"maximum number of concurrent user lookups when the rate limit budget is plentiful",
+)
+var flagCountReviews = flag.Bool(
+	"count_reviews",
+	false,
+	"if true, also counts pull request reviews by external contributors in a separate section; makes a request per pull request",
+)
+var flagReviewsIntermediateOutput = flag.String(
+	"reviews_intermediate_output_file",
+	"reviews_intermediate_output.json",
+	"place where intermediate output of reviews is placed",
+)
+var flagExcludePaths = flag.String(
+	"exclude_paths",
+	"",
+	"if set, comma separated path patterns; commits only changing matching files are excluded (e.g. \"vendor,*.pb.go,docs/*\"); makes a request per commit",
+)
+var flagNoCache = flag.Bool(
+	"no_cache",
+	false,
+	"if true, ignores cached ETags and any scan checkpoint, refetching everything and rewriting the caches from scratch",
+)
+var flagCountDiscussions = flag.Bool(
+	"count_discussions",
+	false,
+	"if true, also counts GitHub Discussions posts by external contributors in a separate section",
+)
+var flagDiscussionsIntermediateOutput = flag.String(
+	"discussions_intermediate_output_file",
+	"discussions_intermediate_output.json",
+	"place where intermediate output of discussions is placed",
+)
+var flagSkipRepoCheck = flag.Bool(
+	"skip_repo_check",
+	false,
+	"if true, does not check every repo exists before scanning",
+)
+var flagMaxCommitsPerRepo = flag.Int(
+	"max_commits_per_repo",
+	0,
+	"if set, only looks at this many of the most recent commits in each repo",
+)
+var flagScanForks = flag.Bool(
+	"scan_forks",
+	false,
+	"experimental: if true, also counts external commits on the default branch of each repo's forks which are not upstream",
+)
+var flagMaxForks = flag.Int(
+	"max_forks",
+	10,
+	"maximum number of forks, most watched first, to scan per repo with -scan_forks",
+)
+var flagAnnotateAlumni = flag.Bool(
+	"annotate_alumni",
+	false,
+	"if true, counts the commits of members of -organization and AUTHORS entries not made with an organization email, marking them and contributors who are now members as alumni",
+)
+var flagCountMode = flag.String(
+	"count_mode",
+	countModeCommits,
+	"what contributors are ranked by: commits, or active_days to count each UTC day with any contributions once",
+)
+var flagShowPercent = flag.Bool(
+	"show_percent",
+	false,
+	"if true, shows each contributor's share of the contributions in each section alongside their count",
+)
+var flagShowSpan = flag.Bool(
+	"show_span",
+	false,
+	"if true, shows how long each contributor has been active, from their first to their last contribution",
+)
+var flagGithubActions = flag.Bool(
+	"github_actions",
+	false,
+	"if true, writes a job summary of the top contributors and sets contributor_count, contribution_count and output_file step outputs when run in GitHub Actions",
+)
+var flagPublishGist = flag.Bool(
+	"publish_gist",
+	false,
+	"if true, publishes the output to a secret gist, updating the one recorded in -gist_state_file if any",
+)
+var flagGistStateFile = flag.String(
+	"gist_state_file",
+	".gist_id",
+	"file recording the ID of the gist -publish_gist updates",
+)
+var flagShowEmailDomain = flag.Bool(
+	"show_email_domain",
+	false,
+	"if true, shows the domain of each contributor's latest commit author email; only the domain is recorded in the intermediate output",
+)
+var flagShowFullEmail = flag.Bool(
+	"show_full_email",
+	false,
+	"if true, shows each contributor's full latest commit author email rather than just the domain; emails are only recorded in the intermediate output with this or -show_email_domain",
+)
+var flagTable = flag.Bool(
+	"table",
+	false,
+	"if true, renders each Markdown section as a table rather than a comma separated list",
+)
+var flagShowCompany = flag.Bool(
+	"show_company",
+	false,
+	"if true, shows the company each contributor reports on their GitHub profile",
+)
+var flagShowLocation = flag.Bool(
+	"show_location",
+	false,
+	"if true, shows the location each contributor reports on their GitHub profile, and a breakdown of contributors by region",
+)
+var flagAvatarSize = flag.Int(
+	"avatar_size",
+	20,
+	"size in pixels of the contributor avatars in -format html",
+)
+var flagMaxRetries = flag.Int(
+	"max_retries",
+	5,
+	"maximum number of times a request failing with a server error (5xx) is retried, with exponential backoff",
+)
+var flagManualContributionsFile = flag.String(
+	"manual_contributions_file",
+	"",
+	"if set, a JSON file of contributions made outside of GitHub to credit alongside the scanned commits",
+)
+var flagIncludeCommitLinks = flag.Bool(
+	"include_commit_links",
+	false,
+	"if true, records the commits counted for each contributor in the intermediate output and lists them with links in JSON output",
+)
+var flagLabelsFile = flag.String(
+	"labels_file",
+	"",
+	"if set, a JSON file mapping logins to lists of labels, shown in JSON output and in a By Label section",
+)
+var flagOnlyYear = flag.Int(
+	"only_year",
+	0,
+	"if set, only renders the Markdown section for this year, e.g. to refresh one year's section after correcting the filters",
+)
+var flagOutputYears = flag.String(
+	"output_years",
+	"",
+	"if set, only renders sections for these comma separated years or year ranges, e.g. \"2019,2022-2024\"; all-time sections still cover every year",
+)
+var flagSeed = flag.Int64(
+	"seed",
+	0,
+	"seed for randomized behavior such as retry jitter, for reproducible runs; by default, seeded from the time",
+)
+var flagVeterans = flag.Int(
+	"veterans",
+	0,
+	"if set, lists this many of the still active contributors who first contributed the longest ago",
+)
+var flagRankBy = flag.String(
+	"rank_by",
+	rankByCount,
+	"how contributors are ordered: count, or impact to weight recent contributions more heavily, each halving in weight every -impact_half_life",
+)
+var flagImpactHalfLife = flag.Duration(
+	"impact_half_life",
+	365*24*time.Hour,
+	"with -rank_by=impact, how long it takes for a contribution's weight to halve",
+)
+var flagActiveWindow = flag.Duration(
+	"active_window",
+	365*24*time.Hour,
+	"how recently before the end date a contributor must have contributed to count as still active",
+)
+var flagLocale = flag.String(
+	"locale",
+	"",
+	"if set, a BCP 47 language tag (e.g. \"de\") used to format counts in Markdown output, e.g. with thousands separators; dates and month and weekday names are not localized",
+)
+var flagYearsAscending = flag.Bool(
+	"years_ascending",
+	false,
+	"if true, lists the yearly sections oldest first",
+)
 
 func getOrganizationLogins(
 	ctx context.Context, ghClient *github.Client, org string,
@@ -103,7 +594,12 @@ func getOrganizationLogins(
 }
 
 func getRepositories(ctx context.Context, ghClient *github.Client) []*github.Repository {
-	opts := &github.RepositoryListByOrgOptions{}
+	opts := &github.RepositoryListByOrgOptions{
+		Type: "public",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
 	more := true
 	var repos []*github.Repository
 	for more {
@@ -124,25 +620,30 @@ func getRepositories(ctx context.Context, ghClient *github.Client) []*github.Rep
 	return repos
 }
 
-func getOrganizationEmailsAndNamesFromAuthors(
-	ctx context.Context, ghClient *github.Client,
-) (map[string]struct{}, map[string]struct{}) {
-	authorsFile, _, _, err := ghClient.Repositories.GetContents(
-		ctx,
-		*flagAuthorsOrg,
-		*flagAuthorsRepo,
-		*flagAuthorsPath,
-		nil,
-	)
-	if err != nil {
-		panic(err)
-	}
-	retEmails := map[string]struct{}{}
-	retLogins := map[string]struct{}{}
-	contents, err := authorsFile.GetContent()
-	if err != nil {
-		panic(err)
+// orgRepoNames returns the names of the public repos in -organization
+// which are neither archived nor forks, sorted.
+func orgRepoNames(ctx context.Context, ghClient *github.Client) []string {
+	var names []string
+	for _, repo := range getRepositories(ctx, ghClient) {
+		if repo.GetArchived() || repo.GetFork() || repo.GetPrivate() {
+			continue
+		}
+		names = append(names, repo.GetName())
 	}
+	sort.Strings(names)
+	return names
+}
+
+// authorsLoginPattern matches a GitHub login annotation in an AUTHORS
+// entry, e.g. "(somelogin)" in "Some Name (somelogin) <some@email>".
+var authorsLoginPattern = regexp.MustCompile(`^\(([A-Za-z0-9][A-Za-z0-9-]*)\)$`)
+
+// parseAuthors adds the emails, names and annotated logins of every
+// cockroachlabs.com entry in the given AUTHORS file contents to emails,
+// names and logins. Logins are lower cased.
+func parseAuthors(
+	contents string, emails map[string]struct{}, names map[string]struct{}, logins map[string]struct{},
+) {
 	lines := strings.Split(contents, "\n")
 	for _, line := range lines {
 		if strings.HasPrefix(line, "#") {
@@ -152,20 +653,89 @@ func getOrganizationEmailsAndNamesFromAuthors(
 			continue
 		}
 		fields := strings.Split(line, " ")
+		var nameFields []string
 		seenEmail := false
-		for i, field := range fields {
+		for _, field := range fields {
+			if match := authorsLoginPattern.FindStringSubmatch(field); match != nil {
+				logins[strings.ToLower(match[1])] = struct{}{}
+				continue
+			}
 			if strings.HasPrefix(field, "<") && strings.HasSuffix(field, ">") {
 				if !seenEmail {
-					retLogins[strings.Join(fields[:i], " ")] = struct{}{}
+					names[strings.Join(nameFields, " ")] = struct{}{}
 				}
 				seenEmail = true
 				email := field[1 : len(field)-1]
-				retEmails[email] = struct{}{}
+				emails[email] = struct{}{}
+				continue
+			}
+			if !seenEmail {
+				nameFields = append(nameFields, field)
+			}
+		}
+	}
+}
+
+// getAuthorsFiles returns the contents of each of the AUTHORS files in
+// -authors_path which exist, keyed by path.
+func getAuthorsFiles(ctx context.Context, ghClient *github.Client) map[string]string {
+	ret := map[string]string{}
+	for _, authorsPath := range strings.Split(*flagAuthorsPath, ",") {
+		authorsFile, _, resp, err := ghClient.Repositories.GetContents(
+			ctx,
+			*flagAuthorsOrg,
+			*flagAuthorsRepo,
+			authorsPath,
+			nil,
+		)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				fmt.Printf("* WARNING: authors file %s not found, skipping\n", authorsPath)
+				continue
 			}
+			panic(err)
+		}
+		contents, err := authorsFile.GetContent()
+		if err != nil {
+			panic(err)
 		}
+		ret[authorsPath] = contents
+	}
+	return ret
+}
+
+// getOrganizationEmailsAndNamesFromAuthors returns the emails, the names
+// and the annotated logins of the organization's entries in the AUTHORS
+// files. The names also include the logins of the members of the
+// cockroachdb and cockroachlabs organizations.
+func getOrganizationEmailsAndNamesFromAuthors(
+	ctx context.Context, ghClient *github.Client,
+) (map[string]struct{}, map[string]struct{}, map[string]struct{}) {
+	retEmails, retLogins, authorsLogins := getAuthors(ctx, ghClient)
+	for login := range getMemberLogins(ctx, ghClient) {
+		retLogins[login] = struct{}{}
+	}
+	return retEmails, retLogins, authorsLogins
+}
+
+// getAuthors returns the emails, the names and the annotated logins of the
+// organization's entries in the AUTHORS files.
+func getAuthors(
+	ctx context.Context, ghClient *github.Client,
+) (map[string]struct{}, map[string]struct{}, map[string]struct{}) {
+	emails := map[string]struct{}{}
+	names := map[string]struct{}{}
+	logins := map[string]struct{}{}
+	for _, contents := range getAuthorsFiles(ctx, ghClient) {
+		parseAuthors(contents, emails, names, logins)
 	}
+	return emails, names, logins
+}
 
-	// Also grab organisation members.
+// getMemberLogins returns the logins of the members of the cockroachdb and
+// cockroachlabs organizations.
+func getMemberLogins(ctx context.Context, ghClient *github.Client) map[string]struct{} {
+	ret := map[string]struct{}{}
 	for _, org := range []string{"cockroachdb", "cockroachlabs"} {
 		opts := &github.ListMembersOptions{
 			ListOptions: github.ListOptions{
@@ -183,7 +753,7 @@ func getOrganizationEmailsAndNamesFromAuthors(
 				panic(err)
 			}
 			for _, member := range members {
-				retLogins[member.GetLogin()] = struct{}{}
+				ret[member.GetLogin()] = struct{}{}
 			}
 			more = resp.NextPage != 0
 			if more {
@@ -191,156 +761,794 @@ func getOrganizationEmailsAndNamesFromAuthors(
 			}
 		}
 	}
+	return ret
+}
 
-	return retEmails, retLogins
+// contribution is a single commit attributed to an external contributor.
+type contribution struct {
+	login string
+	name  string
+	email string
+	repo  string
+	sha   string
+	t     time.Time
 }
 
 type user struct {
-	userURL string
-	login   string
-	name    string
-	times   []time.Time
+	userURL   string
+	avatarURL string
+	login     string
+	name      string
+	times     []time.Time
+	// email is the author email of the user's latest commit, if known.
+	email string
+	// alumni is set if the user has since joined the organization.
+	alumni bool
+	// company is the company on the user's GitHub profile, normalized.
+	company string
+	// location is the free text location on the user's GitHub profile.
+	location string
+	// labels classify the user, per -labels_file.
+	labels []string
+	// manual is the number of times which are manual contributions, per
+	// -manual_contributions_file.
+	manual int
+	// commits are the user's commits, per -include_commit_links.
+	commits []intermediateCommit
 }
 
-func formatContributors(users map[string]user, from time.Time, to time.Time) string {
-	timesByUser := map[string]int{}
-	for u, obj := range users {
-		for _, t := range obj.times {
-			if t.After(from) && t.Before(to) {
-				timesByUser[u] = timesByUser[u] + 1
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"(", `\(`,
+	")", `\)`,
+	"<", `\<`,
+	">", `\>`,
+)
+
+// escapeMarkdown escapes characters in s which would otherwise be
+// interpreted as Markdown, e.g. brackets which would break a link.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// rankedContributor is a contributor and their number of contributions
+// within some window.
+type rankedContributor struct {
+	u     user
+	count int
+}
+
+// countedTimes returns the times between from and to which count as
+// contributions: all of them, or with -count_mode=active_days, only the
+// first on each UTC day.
+func countedTimes(times []time.Time, from time.Time, to time.Time) []time.Time {
+	var ret []time.Time
+	days := map[string]struct{}{}
+	for _, t := range times {
+		if !t.After(from) || !t.Before(to) {
+			continue
+		}
+		if *flagCountMode == countModeActiveDays {
+			day := t.UTC().Format("2006-01-02")
+			if _, ok := days[day]; ok {
+				continue
 			}
+			days[day] = struct{}{}
 		}
+		ret = append(ret, t)
 	}
-	type toSortEntry struct {
-		u     user
-		count int
+	return ret
+}
+
+// rankContributors returns the users with contributions between from and
+// to, most contributions first, or highest impactScore first with
+// -rank_by=impact. With -count_mode=active_days, all contributions on the
+// same UTC day count as one.
+func rankContributors(users map[string]user, from time.Time, to time.Time) []rankedContributor {
+	timesByUser := map[string]int{}
+	for u, obj := range users {
+		if n := len(countedTimes(obj.times, from, to)); n > 0 {
+			timesByUser[u] = n
+		}
 	}
-	var toSort []toSortEntry
+	var ranked []rankedContributor
 	for u, c := range timesByUser {
-		toSort = append(toSort, toSortEntry{u: users[u], count: c})
+		ranked = append(ranked, rankedContributor{u: users[u], count: c})
+	}
+	if *flagRankBy == rankByImpact {
+		scores := map[string]float64{}
+		for _, entry := range ranked {
+			scores[entry.u.login] = impactScore(entry.u, from, to)
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			si, sj := scores[ranked[i].u.login], scores[ranked[j].u.login]
+			if si == sj {
+				return ranked[i].u.login < ranked[j].u.login
+			}
+			return si > sj
+		})
+		return ranked
 	}
-	sort.Slice(toSort, func(i, j int) bool {
-		if toSort[i].count == toSort[j].count {
-			return toSort[i].u.login < toSort[j].u.login
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].count == ranked[j].count {
+			return ranked[i].u.login < ranked[j].u.login
 		}
-		return toSort[i].count > toSort[j].count
+		return ranked[i].count > ranked[j].count
 	})
+	return ranked
+}
+
+// Values for -count_mode.
+const (
+	countModeCommits    = "commits"
+	countModeActiveDays = "active_days"
+)
+
+// countNoun describes what is counted for each contributor, given what a
+// contribution is.
+func countNoun(noun string) string {
+	if *flagCountMode == countModeActiveDays {
+		return "days with " + noun
+	}
+	return noun
+}
 
+// formatContributors renders ranked contributors as Markdown, as prose or
+// with -table as a table. noun describes what a contribution is.
+func formatContributors(ranked []rankedContributor, noun string) string {
 	var ret []string
 	total := 0
-	for _, entry := range toSort {
+	for _, entry := range ranked {
 		total += entry.count
-		ret = append(
-			ret,
-			fmt.Sprintf("[%s](%s) (%d)", entry.u.name, entry.u.userURL, entry.count),
-		)
 	}
-	return fmt.Sprintf("%d contributors, %d commits\n\n", len(toSort), total) + strings.Join(ret, ", ")
+	summary := fmt.Sprintf(
+		"%s contributors, %s %s\n\n",
+		formatCount(len(ranked)),
+		formatCount(total),
+		noun,
+	)
+	if *flagTable {
+		return summary + formatContributorsTable(ranked, noun, total)
+	}
+	for _, entry := range ranked {
+		count := formatCount(entry.count)
+		if *flagShowPercent {
+			count += fmt.Sprintf(", %.1f%%", 100*float64(entry.count)/float64(total))
+		}
+		var formatted string
+		if entry.u.userURL == "" {
+			formatted = fmt.Sprintf("%s (%s)", escapeMarkdown(entry.u.name), count)
+		} else {
+			formatted = fmt.Sprintf("[%s](%s) (%s)", escapeMarkdown(entry.u.name), entry.u.userURL, count)
+		}
+		if email := displayEmail(entry.u.email); email != "" {
+			formatted += fmt.Sprintf(" (%s)", escapeMarkdown(email))
+		}
+		if company := displayCompany(entry.u.company); company != "" {
+			formatted += fmt.Sprintf(" (%s)", escapeMarkdown(company))
+		}
+		if location := displayLocation(entry.u.location); location != "" {
+			formatted += fmt.Sprintf(" (%s)", escapeMarkdown(location))
+		}
+		if entry.u.alumni {
+			formatted += " *alumni*"
+		}
+		if span := contributionSpan(entry.u); *flagShowSpan && span > 0 {
+			formatted += fmt.Sprintf(" (active over %s)", formatSpan(span))
+		}
+		ret = append(ret, formatted)
+	}
+	return summary + strings.Join(ret, ", ")
 }
 
-func intermediateOutputToOutput(
-	ctx context.Context, ghClient *github.Client, start time.Time, end time.Time,
-) {
-	inFile, err := os.Open(*flagIntermediateOutput)
-	if err != nil {
-		panic(err)
+// reportYears returns the years between start and end, restricted to
+// -output_years if set, in the order their sections are rendered.
+func reportYears(start time.Time, end time.Time) []int {
+	var selected map[int]struct{}
+	if *flagOutputYears != "" {
+		var err error
+		if selected, err = parseOutputYears(*flagOutputYears, start, end); err != nil {
+			panic(err)
+		}
 	}
-	defer func() { _ = inFile.Close() }()
-	read, err := ioutil.ReadAll(inFile)
-	if err != nil {
-		panic(err)
+	var years []int
+	for year := end.Year(); year >= start.Year(); year-- {
+		if _, ok := selected[year]; selected != nil && !ok {
+			continue
+		}
+		years = append(years, year)
 	}
-	var usersIn map[string][]string
-	if err := json.Unmarshal(read, &usersIn); err != nil {
-		panic(err)
+	if *flagYearsAscending {
+		sort.Ints(years)
 	}
+	return years
+}
 
-	users := map[string]user{}
-	resultCh := make(chan user, len(usersIn))
-	const userRateLimit = 20
-	rateLimit := make(chan struct{}, userRateLimit)
-	for i := 0; i < userRateLimit; i++ {
-		rateLimit <- struct{}{}
+// yearRange returns the window covering the given year.
+func yearRange(year int) (time.Time, time.Time) {
+	return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// formatSummary summarizes the contributors between start and end in a
+// line, along with the number who contributed in end's year.
+func formatSummary(users map[string]user, start time.Time, end time.Time) string {
+	ranked := rankContributors(users, start, end)
+	total := 0
+	for _, entry := range ranked {
+		total += entry.count
+	}
+	yearFrom, _ := yearRange(end.Year())
+	if yearFrom.Before(start) {
+		yearFrom = start
+	}
+	return fmt.Sprintf(
+		"%s external contributors, %s %s across %s repos (all-time); %s this year",
+		formatCount(len(ranked)),
+		formatCount(total),
+		countNoun("commits"),
+		formatCount(len(repoList())),
+		formatCount(len(rankContributors(users, yearFrom, end))),
+	)
+}
+
+// formatContributorNames lists the names of the ranked contributors
+// alphabetically, without counts.
+func formatContributorNames(ranked []rankedContributor) string {
+	names := make([]string, 0, len(ranked))
+	for _, entry := range ranked {
+		names = append(names, entry.u.name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return strings.Join(names, ", ")
+}
+
+// formatHighlights describes the busiest month and day of the week for
+// contributions between from and to.
+func formatHighlights(users map[string]user, from time.Time, to time.Time) string {
+	byMonth := map[time.Time]int{}
+	byWeekday := map[time.Weekday]int{}
+	for _, obj := range users {
+		for _, t := range obj.times {
+			if t.After(from) && t.Before(to) {
+				t = t.UTC()
+				byMonth[time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)]++
+				byWeekday[t.Weekday()]++
+			}
+		}
+	}
+	if len(byMonth) == 0 {
+		return ""
+	}
+	var busiestMonth time.Time
+	for month, count := range byMonth {
+		if count > byMonth[busiestMonth] ||
+			(count == byMonth[busiestMonth] && month.Before(busiestMonth)) {
+			busiestMonth = month
+		}
 	}
-	blocklisted := map[string]struct{}{}
-	for _, blocked := range strings.Split(*flagBlocklist, ",") {
-		blocklisted[blocked] = struct{}{}
+	busiestWeekday := time.Sunday
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if byWeekday[weekday] > byWeekday[busiestWeekday] {
+			busiestWeekday = weekday
+		}
 	}
+	return fmt.Sprintf(
+		"Busiest month: %s (%s commits). Busiest day: %s (%s commits).",
+		busiestMonth.Format("January 2006"),
+		formatCount(byMonth[busiestMonth]),
+		busiestWeekday,
+		formatCount(byWeekday[busiestWeekday]),
+	)
+}
+
+// lookupUsers resolves the name and URL of every login in usersIn.
+func lookupUsers(
+	ctx context.Context, ghClient *github.Client, usersIn map[string][]time.Time,
+) []user {
+	progress.emit(progressEvent{Event: progressLookupStarted, Count: len(usersIn)})
+	defer progress.emit(progressEvent{Event: progressLookupFinished, Count: len(usersIn)})
+	resultCh := make(chan user, len(usersIn))
+	limiter := newAdaptiveLimiter(*flagMinConcurrency, *flagMaxConcurrency)
 	var wg sync.WaitGroup
-	for u, timesIn := range usersIn {
+	for u, times := range usersIn {
 		wg.Add(1)
-		go func(u string, timesIn []string) {
-			defer func() {
-				wg.Done()
-				rateLimit <- struct{}{}
-			}()
-			<-rateLimit
-			fmt.Printf("** looking up %s\n", u)
-			ghUser, _, err := ghClient.Users.Get(ctx, u)
+		go func(u string, times []time.Time) {
+			defer wg.Done()
+			if isUnlinkedLogin(u) {
+				resultCh <- user{
+					login: u,
+					name:  strings.TrimPrefix(u, unlinkedLoginPrefix),
+					times: times,
+				}
+				return
+			}
+			ghUser, err := lookedUpUsers.get(u, func() (*github.User, error) {
+				fmt.Printf("** looking up %s\n", u)
+				limiter.acquire()
+				ghUser, resp, err := ghClient.Users.Get(ctx, u)
+				limiter.release(resp)
+				return ghUser, err
+			})
 			if err != nil {
 				panic(err)
 			}
-			times := []time.Time{}
-			for _, tIn := range timesIn {
-				t, err := time.Parse(time.RFC3339, tIn)
-				if err != nil {
-					panic(err)
-				}
-				times = append(times, t)
-			}
 			name := ghUser.GetName()
 			if name == "" {
 				name = u
 			}
 			resultCh <- user{
-				userURL: ghUser.GetHTMLURL(),
-				login:   u,
-				name:    name,
-				times:   times,
+				userURL:   ghUser.GetHTMLURL(),
+				avatarURL: ghUser.GetAvatarURL(),
+				login:     u,
+				name:      name,
+				company:   normalizeCompany(ghUser.GetCompany()),
+				location:  ghUser.GetLocation(),
+				times:     times,
 			}
-		}(u, timesIn)
+		}(u, times)
 	}
+	wg.Wait()
+	close(resultCh)
 
-	_, blocklistedNames := getOrganizationEmailsAndNamesFromAuthors(ctx, ghClient)
+	var ret []user
+	for u := range resultCh {
+		ret = append(ret, u)
+	}
+	return ret
+}
 
-	wg.Wait()
-	for i := 0; i < len(usersIn); i++ {
-		u := <-resultCh
-		if _, ok := blocklisted[u.login]; ok {
+// filterUsers returns the users which are allowlisted, or are neither
+// blocklisted by login nor have a name in blocklistedNames.
+func filterUsers(
+	looked []user,
+	allowlisted loginPatterns,
+	blocklisted loginPatterns,
+	blocklistedNames nameBlocklist,
+) map[string]user {
+	users := map[string]user{}
+	for _, u := range looked {
+		if allowlisted.matches(u.login) {
+			users[u.login] = u
+			continue
+		}
+		if blocklisted.matches(u.login) {
 			continue
 		}
-		if _, ok := blocklistedNames[u.name]; ok {
+		if list, ok := blocklistedNames.match(u.name); ok {
+			fmt.Printf(
+				"* WARNING: excluding %s only because their name %q is %s; allowlist them if they are external\n",
+				u.login,
+				u.name,
+				list,
+			)
 			continue
 		}
 		users[u.login] = u
 	}
+	return users
+}
 
-	fromRepos := []string{}
-	for _, repo := range strings.Split(*flagRepos, ",") {
-		fromRepos = append(
-			fromRepos,
-			fmt.Sprintf("[%s](https://github.com/%s/%s)", repo, *flagOrganization, repo),
+func intermediateOutputToOutput(
+	ctx context.Context, ghClient *github.Client, start time.Time, end time.Time, newSince time.Time,
+) {
+	targets, err := outputTargets()
+	if err != nil {
+		panic(err)
+	}
+	usersIn, err := readTimesFile(*flagIntermediateOutput)
+	if err != nil {
+		panic(err)
+	}
+	allowlisted := parseLoginPatterns(*flagAllowlist)
+	blocklisted := parseLoginPatterns(*flagBlocklist)
+	var blocklistedNames nameBlocklist
+	// lookup returns the given users with their profiles, which are all
+	// collected in looked to be cached.
+	var lookup func(map[string][]time.Time) []user
+	var looked []user
+	if *flagRenderOnly {
+		f, err := readIntermediate(*flagIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		if len(f.Profiles) == 0 {
+			panic(errors.Newf(
+				"%s has no cached profiles; run once without -render_only to cache them",
+				*flagIntermediateOutput,
+			))
+		}
+		blocklistedNames = nameBlocklist{
+			authorsNames: map[string]struct{}{},
+			memberLogins: map[string]struct{}{},
+		}
+		for _, profile := range f.Profiles {
+			if profile.InAuthors {
+				blocklistedNames.authorsNames[profile.Name] = struct{}{}
+			}
+			if profile.MemberLogin {
+				blocklistedNames.memberLogins[profile.Name] = struct{}{}
+			}
+		}
+		lookup = func(usersIn map[string][]time.Time) []user {
+			ret, err := cachedUsers(usersIn, f.Profiles)
+			if err != nil {
+				panic(err)
+			}
+			return ret
+		}
+	} else {
+		_, authorsNames, _ := getAuthors(ctx, ghClient)
+		blocklistedNames = nameBlocklist{
+			authorsNames: authorsNames,
+			memberLogins: getMemberLogins(ctx, ghClient),
+		}
+		lookup = func(usersIn map[string][]time.Time) []user {
+			ret := lookupUsers(ctx, ghClient, usersIn)
+			looked = append(looked, ret...)
+			return ret
+		}
+	}
+	users := filterUsers(
+		lookup(usersIn),
+		allowlisted,
+		blocklisted,
+		blocklistedNames,
+	)
+
+	var attempted map[string]user
+	if *flagAttemptedContributors {
+		attemptedIn, err := readTimesFile(*flagAttemptedIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		attempted = filterUsers(
+			lookup(attemptedIn),
+			allowlisted,
+			blocklisted,
+			blocklistedNames,
+		)
+	}
+	var reviewers map[string]user
+	if *flagCountReviews {
+		reviewsIn, err := readTimesFile(*flagReviewsIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		reviewers = filterUsers(
+			lookup(reviewsIn),
+			allowlisted,
+			blocklisted,
+			blocklistedNames,
 		)
 	}
+	var mergers map[string]user
+	if *flagCountMergedPRs {
+		mergedIn, err := readTimesFile(*flagMergedPRsIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		mergers = filterUsers(
+			lookup(mergedIn),
+			allowlisted,
+			blocklisted,
+			blocklistedNames,
+		)
+	}
+	var discussers map[string]user
+	if *flagCountDiscussions {
+		discussionsIn, err := readTimesFile(*flagDiscussionsIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		discussers = filterUsers(
+			lookup(discussionsIn),
+			allowlisted,
+			blocklisted,
+			blocklistedNames,
+		)
+	}
+	if !*flagRenderOnly {
+		if err := cacheProfiles(*flagIntermediateOutput, looked, blocklistedNames); err != nil {
+			panic(err)
+		}
+	}
+	if *flagManualContributionsFile != "" {
+		contributors, err := readManualContributions(*flagManualContributionsFile)
+		if err != nil {
+			panic(err)
+		}
+		addManualContributions(users, contributors)
+	}
+	if *flagOverridesFile != "" {
+		overrides, err := readOverrides(*flagOverridesFile)
+		if err != nil {
+			panic(err)
+		}
+		applyOverrides(users, overrides)
+		applyOverrides(attempted, overrides)
+		applyOverrides(reviewers, overrides)
+		applyOverrides(discussers, overrides)
+		applyOverrides(mergers, overrides)
+	}
+	if *flagLabelsFile != "" {
+		labels, err := readLabels(*flagLabelsFile)
+		if err != nil {
+			panic(err)
+		}
+		applyLabels(users, labels)
+	}
+	if !newSince.IsZero() {
+		// Debuts are taken from the full history rather than the window.
+		for login, u := range users {
+			if firstContribution(u).Before(newSince) {
+				delete(users, login)
+			}
+		}
+	}
 
+	if *flagIncludeCommitLinks {
+		f, err := readIntermediate(*flagIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		if f.Commits == nil && len(users) > 0 {
+			panic(errors.Newf(
+				"%s has no commits recorded; scan with -include_commit_links to record them",
+				*flagIntermediateOutput,
+			))
+		}
+		for login, u := range users {
+			u.commits = f.Commits[login]
+			users[login] = u
+		}
+	}
+	if *flagShowEmailDomain || *flagShowFullEmail {
+		emails, err := readEmails(*flagIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		for login, u := range users {
+			u.email = emails[login]
+			users[login] = u
+		}
+	}
+
+	if *flagAnnotateAlumni {
+		members, err := getOrganizationLogins(ctx, ghClient, *flagOrganization)
+		if err != nil {
+			panic(err)
+		}
+		f, err := readIntermediate(*flagIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		alumni := map[string]struct{}{}
+		for _, login := range f.Alumni {
+			alumni[login] = struct{}{}
+		}
+		for login, u := range users {
+			_, isMember := members[login]
+			_, isAlumni := alumni[login]
+			if isMember || isAlumni {
+				u.alumni = true
+				users[login] = u
+			}
+		}
+	}
+
+	if *flagGithubActions {
+		if err := writeActionsOutputs(users, start, end, targets[0].path); err != nil {
+			panic(err)
+		}
+	}
+
+	if *flagHeatmapFile != "" {
+		if err := writeHeatmap(*flagHeatmapFile, users, start, end); err != nil {
+			panic(err)
+		}
+		fmt.Printf("* Wrote heatmap to %q\n", *flagHeatmapFile)
+	}
+
+	if *flagForLogin != "" {
+		u, ok := users[*flagForLogin]
+		if !ok {
+			panic(errors.Newf("%s has no recorded contributions", *flagForLogin))
+		}
+		f, err := readIntermediate(*flagIntermediateOutput)
+		if err != nil {
+			panic(err)
+		}
+		generatedAt := time.Now().Format(time.RFC3339)
+		out, err := formatPersonalReport(u, f.Commits[u.login], start, end, generatedAt)
+		if err != nil {
+			panic(err)
+		}
+		writeOutput(*flagOutput, out, generatedAt)
+		publishOutput(ctx, ghClient, *flagOutput)
+		return
+	}
+
+	if *flagSummaryOnly {
+		fmt.Println(formatSummary(users, start, end))
+		return
+	}
+
+	for _, target := range targets {
+		out, generatedAt := renderOutput(
+			target.format, users, reviewers, discussers, mergers, attempted, start, end,
+		)
+		writeOutput(target.path, out, generatedAt)
+	}
+	publishOutput(ctx, ghClient, targets[0].path)
+}
+
+// publishOutput publishes the output written to path to a gist, per
+// -publish_gist. It is only called once the output has been written, so a
+// failed run never publishes a stale or partial report.
+func publishOutput(ctx context.Context, ghClient *github.Client, path string) {
+	if !*flagPublishGist {
+		return
+	}
+	if err := publishGist(ctx, ghClient, path, *flagGistStateFile); err != nil {
+		panic(err)
+	}
+}
+
+// renderOutput renders the report in the given format, returning it and
+// the generation time embedded in it, if any. reviewers, discussers,
+// mergers and attempted are nil unless being counted.
+func renderOutput(
+	format string,
+	users map[string]user,
+	reviewers map[string]user,
+	discussers map[string]user,
+	mergers map[string]user,
+	attempted map[string]user,
+	start time.Time,
+	end time.Time,
+) (string, string) {
+	switch format {
+	case "markdown":
+	case "ics":
+		return formatICS(users, time.Now()), ""
+	case "json":
+		generatedAt := time.Now().Format(time.RFC3339)
+		out, err := formatJSON(users, reviewers, discussers, mergers, start, end, generatedAt)
+		if err != nil {
+			panic(err)
+		}
+		return out, generatedAt
+	case "jsonl":
+		out, err := formatJSONL(users, start, end)
+		if err != nil {
+			panic(err)
+		}
+		return out, ""
+	case "html":
+		generatedAt := time.Now().Format(time.RFC3339)
+		out, err := formatHTML(users, start, end, generatedAt)
+		if err != nil {
+			panic(err)
+		}
+		return out, generatedAt
+	case "social":
+		out, err := formatSocial(users, start, end, *flagSocialMaxChars)
+		if err != nil {
+			panic(err)
+		}
+		return out, ""
+	default:
+		panic(fmt.Sprintf("unknown format %q", format))
+	}
+
+	if *flagContributorsOnly {
+		return formatContributorNames(rankContributors(users, start, end)), ""
+	}
+
+	if *flagOnlyYear != 0 {
+		yearFrom, yearTo := yearRange(*flagOnlyYear)
+		generatedAt := time.Now().Format(time.RFC3339)
+		return fmt.Sprintf(
+			`### %d
+
+Last generated at %s.
+
+%s
+`,
+			*flagOnlyYear,
+			generatedAt,
+			formatContributors(rankContributors(users, yearFrom, yearTo), countNoun("commits")),
+		), generatedAt
+	}
+
+	var header string
+	if !*flagNoRepoLinks {
+		fromRepos := []string{}
+		for _, repo := range repoList() {
+			link := fmt.Sprintf(
+				"[%s](%s/%s/%s)",
+				repo,
+				strings.TrimSuffix(*flagGithubBaseURL, "/"),
+				*flagOrganization,
+				repo,
+			)
+			if former, ok := renamedRepos[repo]; ok {
+				link += fmt.Sprintf(" (formerly %s)", former)
+			}
+			fromRepos = append(fromRepos, link)
+		}
+		header = fmt.Sprintf("Contributions from: %s.\n\n", strings.Join(fromRepos, ", "))
+	}
+	if *flagCountMode == countModeActiveDays {
+		header += "Contributors are ranked by the number of distinct days (UTC) they contributed on.\n\n"
+	}
+
+	generatedAt := time.Now().Format(time.RFC3339)
 	out := fmt.Sprintf(
 		`# External Contributors - Hall of Fame
 
 Last generated at %s.
 
-Contributions from: %s.
+%s## All-Time External Contributors
 
-## All-Time External Contributors
+%s
 
 %s
 
-## By Year
 `,
-		time.Now().Format(time.RFC3339),
-		strings.Join(fromRepos, ", "),
-		formatContributors(users, start, end),
+		generatedAt,
+		header,
+		formatHighlights(users, start, end),
+		formatContributors(rankContributors(users, start, end), countNoun("commits")),
 	)
-	for year := end.Year(); year >= start.Year(); year-- {
+	if *flagVeterans > 0 {
+		out += fmt.Sprintf(
+			`### Veterans
+
+The longest standing contributors who are still active.
+
+%s
+
+`,
+			formatVeterans(users, end, *flagVeterans),
+		)
+	}
+	if *flagCohorts {
+		out += fmt.Sprintf(
+			`## Retention by First Contribution Year
+
+Percentage of each year's new contributors who contributed again N years later.
+
+%s
+`,
+			formatCohorts(users, start, end),
+		)
+	}
+	if *flagLabelsFile != "" {
+		out += "## By Label\n\n" + formatLabels(users, start, end)
+	}
+	if *flagShowLocation {
+		out += fmt.Sprintf(
+			`## By Region
+
+Based on the locations contributors give on their profiles.
+
+%s
+
+`,
+			formatRegions(users, start, end),
+		)
+	}
+	out += "## By Year\n"
+	for _, year := range reportYears(start, end) {
+		yearFrom, yearTo := yearRange(year)
 		out += fmt.Sprintf(
 			`### %d
 
@@ -348,16 +1556,66 @@ Contributions from: %s.
 
 `,
 			year,
-			formatContributors(
-				users,
-				time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC),
-				time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC),
-			),
+			formatContributors(rankContributors(users, yearFrom, yearTo), countNoun("commits")),
 		)
 	}
+	if reviewers != nil {
+		out += fmt.Sprintf(
+			`## Reviews
+
+Approving and commenting pull request reviews by external contributors.
+
+%s
+
+`,
+			formatContributors(rankContributors(reviewers, start, end), countNoun("reviews")),
+		)
+	}
+	if discussers != nil {
+		out += fmt.Sprintf(
+			`## Discussions
+
+Discussions and discussion comments by external contributors.
+
+%s
+
+`,
+			formatContributors(rankContributors(discussers, start, end), countNoun("posts")),
+		)
+	}
+	if attempted != nil {
+		out += fmt.Sprintf(
+			`## Attempted Contributors
+
+Authors of pull requests which were not merged. These are not counted as contributions above.
+
+%s
+`,
+			formatContributors(rankContributors(attempted, start, end), countNoun("pull requests")),
+		)
+	}
+
+	return out, generatedAt
+}
 
+// writeOutput prints out and writes it to path. generatedAt is the
+// generation time embedded in out, if any.
+func writeOutput(path string, out string, generatedAt string) {
 	fmt.Printf("%s\n", out)
-	outFile, err := os.OpenFile(*flagOutput, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	// The generation time differs on every run, so it is left out of the
+	// hash.
+	hash := contentHash(strings.Replace(out, generatedAt, "", 1))
+	if *flagSkipUnchanged {
+		unchanged, err := contentUnchanged(path, hash)
+		if err != nil {
+			panic(err)
+		}
+		if unchanged {
+			fmt.Printf("* No changes to %q, skipping write\n", path)
+			return
+		}
+	}
+	outFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		panic(err)
 	}
@@ -367,22 +1625,173 @@ Contributions from: %s.
 	if err := outFile.Close(); err != nil {
 		panic(err)
 	}
-	fmt.Printf("* Output to %q\n", *flagOutput)
+	if *flagSkipUnchanged {
+		if err := writeContentHash(path, hash); err != nil {
+			panic(err)
+		}
+	}
+	fmt.Printf("* Output to %q\n", path)
 }
 
 func main() {
 	flag.Parse()
 
+	if *flagParallelSafe {
+		release, err := acquireLock(*flagLockFile, *flagLockWait)
+		if err != nil {
+			panic(err)
+		}
+		defer release()
+	}
+
+	if *flagProgressJSON != "" {
+		closeProgress, err := progress.open(*flagProgressJSON)
+		if err != nil {
+			panic(err)
+		}
+		defer closeProgress()
+		defer func() {
+			if r := recover(); r != nil {
+				progress.emit(progressEvent{Event: progressFailed})
+				panic(r)
+			}
+			progress.emit(progressEvent{Event: progressDone})
+		}()
+	}
+
+	if *flagMergeIntermediates != "" {
+		if err := mergeTimesFiles(
+			strings.Split(*flagMergeIntermediates, ","),
+			*flagIntermediateOutput,
+		); err != nil {
+			panic(err)
+		}
+		fmt.Printf("* Merged intermediate output to %q\n", *flagIntermediateOutput)
+		return
+	}
+
 	ctx := context.Background()
-	ghClient, err := getGithubClient()
-	if err != nil {
-		panic(err)
+	var etags *etagCache
+	if *flagETagCacheFile != "" {
+		if *flagNoCache {
+			etags = newETagCache()
+		} else {
+			var err error
+			etags, err = loadETagCache(*flagETagCacheFile)
+			if err != nil {
+				panic(err)
+			}
+		}
+		defer func() {
+			if err := etags.save(*flagETagCacheFile); err != nil {
+				panic(err)
+			}
+		}()
+	}
+	var ghClient *github.Client
+	if *flagRenderOnly {
+		if !*flagUseIntermediate {
+			panic("-render_only requires -use_intermediate")
+		}
+		if *flagAnnotateAlumni || *flagPublishGist || *flagReposFromOrg {
+			panic("-annotate_alumni, -publish_gist and -repos_from_org make requests, so cannot be used with -render_only")
+		}
+	} else {
+		var err error
+		seed := *flagSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		fmt.Printf("* Using seed %d\n", seed)
+		ghClient, err = getGithubClient(etags, newLockedRand(seed))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *flagReposFromOrg {
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "repos" {
+				panic("-repos_from_org and -repos are mutually exclusive")
+			}
+		})
+		repos := orgRepoNames(ctx, ghClient)
+		fmt.Printf("* Found %d repos in %s: %s\n", len(repos), *flagOrganization, strings.Join(repos, ", "))
+		if err := flag.Set("repos", strings.Join(repos, ",")); err != nil {
+			panic(err)
+		}
+	}
+
+	if *flagLintAuthors {
+		files := getAuthorsFiles(ctx, ghClient)
+		seen := map[string]string{}
+		findings := 0
+		for _, authorsPath := range strings.Split(*flagAuthorsPath, ",") {
+			contents, ok := files[authorsPath]
+			if !ok {
+				continue
+			}
+			for _, finding := range lintAuthors(authorsPath, contents, seen) {
+				fmt.Println(finding)
+				findings++
+			}
+		}
+		fmt.Printf("* %d anomalies found in AUTHORS\n", findings)
+		return
+	}
+
+	if *flagListMembers {
+		members, err := getOrganizationLogins(ctx, ghClient, *flagOrganization)
+		if err != nil {
+			panic(err)
+		}
+		logins := make([]string, 0, len(members))
+		for login := range members {
+			logins = append(logins, login)
+		}
+		sort.Strings(logins)
+		for _, login := range logins {
+			fmt.Println(login)
+		}
+		fmt.Printf("* %d members of %s\n", len(logins), *flagOrganization)
+		return
+	}
+
+	if *flagLocale != "" {
+		if _, err := language.Parse(*flagLocale); err != nil {
+			panic(fmt.Sprintf("invalid locale %q: %v", *flagLocale, err))
+		}
+	}
+	if *flagCountMode != countModeCommits && *flagCountMode != countModeActiveDays {
+		panic(fmt.Sprintf("unknown count mode %q", *flagCountMode))
+	}
+	switch *flagOnBudgetExceeded {
+	case "", budgetWarn, budgetAbort, budgetWait:
+	default:
+		panic(fmt.Sprintf("unknown -on_budget_exceeded %q", *flagOnBudgetExceeded))
+	}
+	if *flagRankBy != rankByCount && *flagRankBy != rankByImpact {
+		panic(fmt.Sprintf("unknown rank by %q", *flagRankBy))
+	}
+	if *flagImpactHalfLife <= 0 {
+		panic("-impact_half_life must be positive")
+	}
+	if *flagSinceDays < 0 {
+		panic("-since_days must not be negative")
 	}
 
 	start, err := time.Parse("2006-01-02", *flagStartDate)
 	if err != nil {
 		panic(fmt.Sprintf("invalid start date %s: %v", *flagStartDate, err))
 	}
+	if *flagSinceDays > 0 {
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "start_date" {
+				panic("-since_days and -start_date are mutually exclusive")
+			}
+		})
+		start = time.Now().Add(-time.Duration(*flagSinceDays) * 24 * time.Hour)
+	}
 	end := time.Now()
 	if *flagEndDate != "" {
 		end, err = time.Parse("2006-01-02", *flagEndDate)
@@ -391,108 +1800,280 @@ func main() {
 		}
 		end = end.AddDate(0, 0, 1).Add(-time.Second)
 	}
+	var newSince time.Time
+	if *flagNewSince != "" {
+		newSince, err = time.Parse("2006-01-02", *flagNewSince)
+		if err != nil {
+			panic(fmt.Sprintf("invalid new since date %s: %v", *flagNewSince, err))
+		}
+	}
+	if *flagOnlyYear != 0 {
+		if *flagOnlyYear < start.Year() || *flagOnlyYear > end.Year() {
+			panic(fmt.Sprintf(
+				"-only_year %d is outside of the scanned years %d-%d",
+				*flagOnlyYear,
+				start.Year(),
+				end.Year(),
+			))
+		}
+		targets, err := outputTargets()
+		if err != nil {
+			panic(err)
+		}
+		for _, target := range targets {
+			if target.format != "markdown" {
+				panic(fmt.Sprintf("-only_year only applies to markdown output, not %s", target.format))
+			}
+		}
+	}
+	if *flagOutputYears != "" {
+		// Validated up front rather than failing after a scan.
+		if _, err := parseOutputYears(*flagOutputYears, start, end); err != nil {
+			panic(err)
+		}
+	}
+
+	targets, err := outputTargets()
+	if err != nil {
+		panic(err)
+	}
+	outputPaths := []string{*flagOutput}
+	if *flagOutputs != "" {
+		outputPaths = nil
+		for _, target := range targets {
+			outputPaths = append(outputPaths, target.path)
+		}
+	}
+
+	if *flagManifest != "" {
+		defer func() {
+			// A failed run is recorded as such rather than vouching for
+			// whatever outputs it left behind, then fails as before.
+			failure := recover()
+			if err := writeManifest(
+				*flagManifest,
+				start,
+				end,
+				append(outputPaths, *flagIntermediateOutput, *flagSQLiteFile),
+				failure,
+			); err != nil {
+				if failure == nil {
+					panic(err)
+				}
+				fmt.Printf("* WARNING: %v\n", err)
+			} else {
+				fmt.Printf("* Wrote manifest to %q\n", *flagManifest)
+			}
+			if failure != nil {
+				panic(failure)
+			}
+		}()
+	}
 
 	if *flagUseIntermediate {
-		intermediateOutputToOutput(ctx, ghClient, start, end)
+		intermediateOutputToOutput(ctx, ghClient, start, end, newSince)
 		return
 	}
 
-	organizationMembers, err := getOrganizationLogins(ctx, ghClient, *flagOrganization)
+	if *flagCompareOrgs != "" {
+		table, err := compareOrgs(ctx, ghClient, strings.Split(*flagCompareOrgs, ","), start, end)
+		if err != nil {
+			panic(err)
+		}
+		generatedAt := time.Now().Format(time.RFC3339)
+		writeOutput(*flagOutput, fmt.Sprintf(
+			`# External Contributors - Organization Comparison
+
+Last generated at %s.
+
+%s`,
+			generatedAt,
+			table,
+		), generatedAt)
+		return
+	}
+
+	renames, err := validateTargets(
+		ctx,
+		ghClient,
+		*flagOrganization,
+		repoList(),
+		!*flagSkipRepoCheck,
+	)
 	if err != nil {
 		panic(err)
 	}
+	if len(renames) > 0 {
+		repos := repoList()
+		for i, repo := range repos {
+			if renamed, ok := renames[repo]; ok {
+				fmt.Printf("* Repo %s/%s has been renamed to %s, scanning it under that name\n", *flagOrganization, repo, renamed)
+				repos[i] = renamed
+				renamedRepos[renamed] = repo
+			}
+		}
+		if err := flag.Set("repos", strings.Join(repos, ",")); err != nil {
+			panic(err)
+		}
+	}
 
-	emails, names := getOrganizationEmailsAndNamesFromAuthors(ctx, ghClient)
+	if *flagOnBudgetExceeded != "" {
+		if err := checkBudget(
+			ctx,
+			ghClient,
+			*flagOrganization,
+			repoList(),
+			start,
+			*flagOnBudgetExceeded,
+		); err != nil {
+			panic(err)
+		}
+	}
 
-	// Go through each repo.
-	users := map[string]*github.User{}
-	userTimes := map[string][]time.Time{}
+	organizationMembers, err := getOrganizationLogins(ctx, ghClient, *flagOrganization)
+	if err != nil {
+		panic(err)
+	}
 
-	for _, repo := range strings.Split(*flagRepos, ",") {
-		fmt.Printf("* Looking at repo %s\n", repo)
-		opts := &github.CommitsListOptions{
-			ListOptions: github.ListOptions{
-				PerPage: 1000,
-			},
-			Since: start,
-			Until: end,
+	emails, names, authorsLogins := getOrganizationEmailsAndNamesFromAuthors(ctx, ghClient)
+
+	s := newScanner(
+		*flagOrganization,
+		start,
+		end,
+		organizationMembers,
+		emails,
+		names,
+		parseLoginPatterns(*flagBlocklist),
+		parseLoginPatterns(*flagAllowlist),
+	)
+	s.authorsLogins = authorsLogins
+	if *flagExcludePaths != "" {
+		s.excludePaths = strings.Split(*flagExcludePaths, ",")
+	}
+	if *flagInternalLoginsFile != "" {
+		internalLogins, err := readListFile(*flagInternalLoginsFile)
+		if err != nil {
+			panic(err)
 		}
-		more := true
-		for more {
-			commits, resp, err := ghClient.Repositories.ListCommits(
-				ctx,
-				*flagOrganization,
-				repo,
-				opts,
-			)
-			if err != nil {
+		s.internalLogins = internalLogins
+	}
+	s.excludedEmails = map[string]struct{}{}
+	for _, email := range strings.Split(*flagExcludeEmails, ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			s.excludedEmails[strings.ToLower(email)] = struct{}{}
+		}
+	}
+	if *flagExcludeEmailsFile != "" {
+		emails, err := readListFile(*flagExcludeEmailsFile)
+		if err != nil {
+			panic(err)
+		}
+		for email := range emails {
+			s.excludedEmails[strings.ToLower(email)] = struct{}{}
+		}
+	}
+	if *flagCheckpointFile != "" {
+		if *flagParallelRepos > 1 {
+			panic("-checkpoint_file cannot be used with -parallel_repos")
+		}
+		if *flagNoCache {
+			if err := os.Remove(*flagCheckpointFile); err != nil && !os.IsNotExist(err) {
 				panic(err)
 			}
-			for _, commit := range commits {
-				d := commit.GetCommit().GetAuthor().GetDate()
-				if start.After(d) || d.After(end) {
-					continue
-				}
-				if len(commit.GetCommit().Parents) > 0 {
-					continue
-				}
-				if commit.GetAuthor().GetLogin() == "" {
-					continue
-				}
-				if _, ok := organizationMembers[commit.GetAuthor().GetLogin()]; ok {
-					continue
-				}
-				if strings.Contains(commit.GetCommit().GetAuthor().GetEmail(), "@cockroachlabs.com") {
-					continue
-				}
-				if strings.HasPrefix(commit.GetCommit().GetMessage(), "Merge pull request ") {
-					continue
-				}
-				if _, ok := names[commit.GetAuthor().GetName()]; ok {
-					continue
-				}
-				if _, ok := emails[commit.GetCommit().GetAuthor().GetEmail()]; ok {
-					continue
-				}
-				fmt.Printf(
-					"* found commit by %s (%s)) on %s\n",
-					commit.GetAuthor().GetLogin(),
-					commit.GetCommit().GetAuthor().GetEmail(),
-					commit.Commit.GetAuthor().GetDate().Format(time.RFC3339),
-				)
-				users[commit.GetAuthor().GetLogin()] = commit.GetAuthor()
-				userTimes[commit.GetAuthor().GetLogin()] = append(
-					userTimes[commit.GetAuthor().GetLogin()],
-					commit.Commit.GetAuthor().GetDate(),
-				)
+		}
+		if err := s.loadCheckpoint(*flagCheckpointFile); err != nil {
+			panic(err)
+		}
+	}
+	if *flagLogins != "" {
+		// Only the given logins' commits are looked for, across the
+		// organization rather than just -repos.
+		for _, login := range strings.Split(*flagLogins, ",") {
+			if login = strings.TrimSpace(login); login == "" {
+				continue
 			}
-			more = resp.NextPage != 0
-			if more {
-				opts.Page = resp.NextPage
+			if err := s.scanSearch(ctx, ghClient, "author:"+login); err != nil {
+				panic(err)
 			}
 		}
-	}
-
-	intermediateOutputFile, err := os.OpenFile(*flagIntermediateOutput, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
+	} else if err := s.scanRepos(
+		ctx,
+		ghClient,
+		repoList(),
+		*flagParallelRepos,
+		*flagFailFast,
+	); err != nil {
 		panic(err)
 	}
-	commitTimes := map[string][]string{}
-	for user, times := range userTimes {
-		for _, t := range times {
-			commitTimes[user] = append(commitTimes[user], t.Format(time.RFC3339))
+	if *flagSearchQuery != "" {
+		if err := s.scanSearch(ctx, ghClient, *flagSearchQuery); err != nil {
+			panic(err)
 		}
 	}
-	b, err := json.Marshal(commitTimes)
-	if err != nil {
+	if err := s.clearCheckpoint(); err != nil {
 		panic(err)
 	}
-	if _, err := intermediateOutputFile.Write(b); err != nil {
-		panic(err)
+
+	if *flagResolveUnlinked {
+		if err := s.resolveUnlinked(ctx, ghClient); err != nil {
+			panic(err)
+		}
+	}
+	if *flagRequireMergedPR {
+		if err := s.requireMergedPR(ctx, ghClient); err != nil {
+			panic(err)
+		}
+	}
+	if *flagVerboseExclude {
+		fmt.Printf("* %s\n", s.exclusionSummary())
+	}
+	if *flagReportBlocklistHits {
+		fmt.Printf("* Commits excluded by each blocklist entry:\n%s\n", s.blocklistReport())
+	}
+	if *flagPreview {
+		const previewSize = 25
+		fmt.Printf("* Top %d contributors:\n%s\n", previewSize, s.preview(previewSize))
+		return
+	}
+
+	meta := intermediateFile{Emails: recordedEmails(s.authorEmails())}
+	if *flagIncludeCommitLinks {
+		meta.Commits = s.commitsByLogin()
+	}
+	if *flagAnnotateAlumni {
+		meta.Alumni = s.alumniLogins()
 	}
-	if err := intermediateOutputFile.Close(); err != nil {
+	if err := writeIntermediate(*flagIntermediateOutput, s.userTimes, meta); err != nil {
 		panic(err)
 	}
+	if *flagAttemptedContributors {
+		if err := writeTimesFile(*flagAttemptedIntermediateOutput, s.attempted); err != nil {
+			panic(err)
+		}
+	}
+	if *flagCountMergedPRs {
+		if err := writeTimesFile(*flagMergedPRsIntermediateOutput, s.mergedPRs); err != nil {
+			panic(err)
+		}
+	}
+	if *flagCountReviews {
+		if err := writeTimesFile(*flagReviewsIntermediateOutput, s.reviews); err != nil {
+			panic(err)
+		}
+	}
+	if *flagCountDiscussions {
+		if err := writeTimesFile(*flagDiscussionsIntermediateOutput, s.discussions); err != nil {
+			panic(err)
+		}
+	}
+
+	if *flagSQLiteFile != "" {
+		if err := writeSQLite(*flagSQLiteFile, s.users, s.contributions); err != nil {
+			panic(err)
+		}
+		fmt.Printf("* Wrote SQLite export to %q\n", *flagSQLiteFile)
+	}
 
-	intermediateOutputToOutput(ctx, ghClient, start, end)
+	intermediateOutputToOutput(ctx, ghClient, start, end, newSince)
 }
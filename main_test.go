@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var flagUpdateGolden = flag.Bool("update", false, "if true, updates the golden files in testdata")
+
+func TestEscapeMarkdown(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected string
+	}{
+		{in: "Jane Doe", expected: "Jane Doe"},
+		{in: "[bot] name", expected: `\[bot\] name`},
+		{in: "Name (Nick)", expected: `Name \(Nick\)`},
+		{in: "snake_case_name", expected: `snake\_case\_name`},
+		{in: `*star* \ <tag>`, expected: `\*star\* \\ \<tag\>`},
+	} {
+		if got := escapeMarkdown(tc.in); got != tc.expected {
+			t.Errorf("escapeMarkdown(%q) = %q, expected %q", tc.in, got, tc.expected)
+		}
+	}
+}
+
+func TestFormatContributorsEscapesLinks(t *testing.T) {
+	ranked := []rankedContributor{
+		{u: user{name: "[a](b)_c", userURL: "https://github.com/abc"}, count: 2},
+		{u: user{name: "no_link"}, count: 1},
+	}
+	expected := "2 contributors, 3 commits\n\n" +
+		`[\[a\]\(b\)\_c](https://github.com/abc) (2), no\_link (1)`
+	if got := formatContributors(ranked, "commits"); got != expected {
+		t.Errorf("formatContributors() = %q, expected %q", got, expected)
+	}
+}
+
+func testUsers() map[string]user {
+	at := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	return map[string]user{
+		"alice": {
+			login:   "alice",
+			name:    "Alice",
+			userURL: "https://github.com/alice",
+			times: []time.Time{
+				at("2019-03-01T10:00:00Z"),
+				at("2020-03-01T10:00:00Z"),
+				at("2020-03-01T11:00:00Z"),
+			},
+		},
+		"bob": {
+			login:   "bob",
+			name:    "Bob",
+			userURL: "https://github.com/bob",
+			times: []time.Time{
+				at("2020-05-01T10:00:00Z"),
+				at("2020-06-01T10:00:00Z"),
+			},
+		},
+		"carol": {
+			login: "carol",
+			name:  "Carol",
+			times: []time.Time{
+				at("2020-07-01T10:00:00Z"),
+				at("2020-07-02T10:00:00Z"),
+			},
+		},
+		"dave": {
+			login: "dave",
+			name:  "Dave",
+			times: []time.Time{at("2018-01-01T10:00:00Z")},
+		},
+	}
+}
+
+func rankedLogins(ranked []rankedContributor) string {
+	var parts []string
+	for _, entry := range ranked {
+		parts = append(parts, fmt.Sprintf("%s:%d", entry.u.login, entry.count))
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestRankContributors(t *testing.T) {
+	users := testUsers()
+	from := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Most contributions first, ties broken by login, and contributions
+	// outside the window are not counted.
+	if got, expected := rankedLogins(rankContributors(users, from, to)), "alice:3,bob:2,carol:2"; got != expected {
+		t.Errorf("rankContributors() = %s, expected %s", got, expected)
+	}
+	yearFrom, yearTo := yearRange(2019)
+	if got, expected := rankedLogins(rankContributors(users, yearFrom, yearTo)), "alice:1"; got != expected {
+		t.Errorf("rankContributors(2019) = %s, expected %s", got, expected)
+	}
+
+	*flagCountMode = countModeActiveDays
+	defer func() { *flagCountMode = countModeCommits }()
+	if got, expected := rankedLogins(rankContributors(users, from, to)), "alice:2,bob:2,carol:2"; got != expected {
+		t.Errorf("rankContributors() counting active days = %s, expected %s", got, expected)
+	}
+}
+
+func TestRenderMarkdownGolden(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC)
+	out, generatedAt := renderOutput("markdown", testUsers(), nil, nil, nil, nil, start, end)
+	out = strings.Replace(out, generatedAt, "GENERATED_AT", 1)
+
+	const path = "testdata/report.md"
+	if *flagUpdateGolden {
+		if err := ioutil.WriteFile(path, []byte(out), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != string(expected) {
+		t.Errorf("Markdown output differs from %s; rerun with -update if intended:\n%s", path, out)
+	}
+}
+
+func TestFilterUsers(t *testing.T) {
+	looked := []user{
+		{login: "external", name: "External Contributor"},
+		{login: "blocked", name: "Blocked User"},
+		{login: "employee", name: "Named Employee"},
+		{login: "allowed", name: "Named Employee"},
+		{login: "renovate[bot]", name: "Renovate"},
+		{login: "namesake", name: "staffer"},
+	}
+	users := filterUsers(
+		looked,
+		parseLoginPatterns("allowed"),
+		parseLoginPatterns("blocked,*[bot]"),
+		nameBlocklist{
+			authorsNames: map[string]struct{}{"Named Employee": {}},
+			memberLogins: map[string]struct{}{"staffer": {}},
+		},
+	)
+
+	var survivors []string
+	for login := range users {
+		survivors = append(survivors, login)
+	}
+	sort.Strings(survivors)
+	if got, expected := strings.Join(survivors, ","), "allowed,external"; got != expected {
+		t.Errorf("filterUsers() kept %s, expected %s", got, expected)
+	}
+}
+
+func sortedKeys(m map[string]struct{}) string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func TestParseAuthors(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		contents       string
+		expectedEmails string
+		expectedNames  string
+		expectedLogins string
+	}{
+		{
+			name:           "without login",
+			contents:       "Jane Doe <jane@cockroachlabs.com>",
+			expectedEmails: "jane@cockroachlabs.com",
+			expectedNames:  "Jane Doe",
+		},
+		{
+			name:           "with login",
+			contents:       "Jane Doe (JaneDoe) <jane@cockroachlabs.com> <jane@example.com>",
+			expectedEmails: "jane@cockroachlabs.com,jane@example.com",
+			expectedNames:  "Jane Doe",
+			expectedLogins: "janedoe",
+		},
+		{
+			name:           "bot with login",
+			contents:       "Release Bot (crl-release-bot) <release@cockroachlabs.com>",
+			expectedEmails: "release@cockroachlabs.com",
+			expectedNames:  "Release Bot",
+			expectedLogins: "crl-release-bot",
+		},
+		{
+			name:     "comments and external entries are ignored",
+			contents: "# Jane Doe (janedoe) <jane@cockroachlabs.com>\nOutside Person (outsider) <outsider@example.com>",
+		},
+		{
+			name:           "parenthesized words are not a login",
+			contents:       "John (the Third) <john@cockroachlabs.com>",
+			expectedEmails: "john@cockroachlabs.com",
+			expectedNames:  "John (the Third)",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			emails, names, logins := map[string]struct{}{}, map[string]struct{}{}, map[string]struct{}{}
+			parseAuthors(tc.contents, emails, names, logins)
+			if got := sortedKeys(emails); got != tc.expectedEmails {
+				t.Errorf("emails = %s, expected %s", got, tc.expectedEmails)
+			}
+			if got := sortedKeys(names); got != tc.expectedNames {
+				t.Errorf("names = %s, expected %s", got, tc.expectedNames)
+			}
+			if got := sortedKeys(logins); got != tc.expectedLogins {
+				t.Errorf("logins = %s, expected %s", got, tc.expectedLogins)
+			}
+		})
+	}
+}
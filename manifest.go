@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// manifest records the configuration which produced a set of outputs,
+// alongside the checksums of those outputs.
+type manifest struct {
+	GeneratedAt string `json:"generated_at"`
+	// Status is manifestSucceeded, or manifestFailed if the run failed, in
+	// which case Error holds why and no outputs are vouched for.
+	Status          string            `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	ToolVersion     string            `json:"tool_version"`
+	Organization    string            `json:"organization"`
+	Repos           []string          `json:"repos"`
+	BlocklistSHA256 string            `json:"blocklist_sha256"`
+	StartDate       string            `json:"start_date"`
+	EndDate         string            `json:"end_date"`
+	Outputs         map[string]string `json:"outputs"`
+}
+
+const (
+	manifestSucceeded = "succeeded"
+	manifestFailed    = "failed"
+)
+
+// toolVersion returns the VCS revision the binary was built from if known,
+// otherwise the module version.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return info.Main.Version
+}
+
+func fileSHA256(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return contentHash(string(b)), nil
+}
+
+// writeManifest writes a manifest covering each of the given output files
+// which exists. If failure is non-nil, the manifest records the run as failed
+// and covers no outputs, as they may be stale or partially written.
+func writeManifest(
+	path string, start time.Time, end time.Time, outputs []string, failure interface{},
+) error {
+	m := manifest{
+		GeneratedAt:     time.Now().Format(time.RFC3339),
+		Status:          manifestSucceeded,
+		ToolVersion:     toolVersion(),
+		Organization:    *flagOrganization,
+		Repos:           repoList(),
+		BlocklistSHA256: contentHash(*flagBlocklist),
+		StartDate:       start.Format(time.RFC3339),
+		EndDate:         end.Format(time.RFC3339),
+		Outputs:         map[string]string{},
+	}
+	if failure != nil {
+		m.Status = manifestFailed
+		m.Error = fmt.Sprint(failure)
+		outputs = nil
+	}
+	for _, output := range outputs {
+		if output == "" {
+			continue
+		}
+		h, err := fileSHA256(output)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return errors.Wrapf(err, "error hashing %s", output)
+		}
+		m.Outputs[output] = h
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, b, 0644), "error writing manifest %s", path)
+}
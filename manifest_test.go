@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestWriteManifestStatus(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.md")
+	if err := ioutil.WriteFile(output, []byte("report"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name            string
+		failure         interface{}
+		expectedStatus  string
+		expectedError   string
+		expectedOutputs int
+	}{
+		{name: "succeeded", expectedStatus: manifestSucceeded, expectedOutputs: 1},
+		{
+			name:           "failed",
+			failure:        errors.New("error listing commits"),
+			expectedStatus: manifestFailed,
+			expectedError:  "error listing commits",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name+".json")
+			missing := filepath.Join(dir, "missing.md")
+			if err := writeManifest(path, start, end, []string{output, missing, ""}, tc.failure); err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m manifest
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Fatal(err)
+			}
+			if m.Status != tc.expectedStatus || m.Error != tc.expectedError {
+				t.Errorf("status = %q (%q), expected %q (%q)", m.Status, m.Error, tc.expectedStatus, tc.expectedError)
+			}
+			if len(m.Outputs) != tc.expectedOutputs {
+				t.Errorf("expected %d outputs, got %v", tc.expectedOutputs, m.Outputs)
+			}
+			if tc.expectedOutputs > 0 && m.Outputs[output] != contentHash("report") {
+				t.Errorf("unexpected checksum for %s: %s", output, m.Outputs[output])
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// manualContributor credits contributions made outside of GitHub, such as
+// translations, blog posts or talks.
+type manualContributor struct {
+	Login string      `json:"login"`
+	Name  string      `json:"name"`
+	URL   string      `json:"url"`
+	Times []time.Time `json:"times"`
+}
+
+// readManualContributions reads a JSON file listing manual contributions,
+// e.g.
+//
+//	[{"login": "somelogin", "name": "Some Name", "url": "https://example.com", "times": ["2020-01-02T00:00:00Z"]}]
+func readManualContributions(path string) ([]manualContributor, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var contributors []manualContributor
+	if err := json.Unmarshal(b, &contributors); err != nil {
+		return nil, errors.Wrapf(err, "error parsing manual contributions %s", path)
+	}
+	for i, c := range contributors {
+		if c.Login == "" {
+			return nil, errors.Newf("manual contribution %d in %s has no login", i, path)
+		}
+	}
+	return contributors, nil
+}
+
+// addManualContributions adds the manual contributions to users. Users who
+// also have scanned contributions keep their looked up name and URL.
+func addManualContributions(users map[string]user, contributors []manualContributor) {
+	for _, c := range contributors {
+		u, ok := users[c.Login]
+		if !ok {
+			u = user{login: c.Login, name: c.Name, userURL: c.URL}
+			if u.name == "" {
+				u.name = c.Login
+			}
+		}
+		u.times = append(u.times, c.Times...)
+		u.manual += len(c.Times)
+		users[c.Login] = u
+	}
+}
+
+// contributionSource describes where a user's contributions came from:
+// "github", "manual", or "mixed" for both.
+func contributionSource(u user) string {
+	switch u.manual {
+	case 0:
+		return "github"
+	case len(u.times):
+		return "manual"
+	default:
+		return "mixed"
+	}
+}
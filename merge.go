@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// mergeTimesFiles unions the intermediate files at paths into a single
+// intermediate file at outPath. The intermediate format carries no commit
+// SHAs, so a timestamp a user has in several files is counted as many times
+// as it appears in whichever single file has it most. This makes merging
+// overlapping scans idempotent, at the cost of collapsing commits made in
+// the same second to different repos. Where files record different author
+// emails or profiles for a login, the last file's wins. Recorded commits
+// are deduplicated by SHA, and alumni are unioned.
+func mergeTimesFiles(paths []string, outPath string) error {
+	counts := map[string]map[string]int{}
+	var emails map[string]string
+	var profiles map[string]intermediateProfile
+	var commits map[string][]intermediateCommit
+	alumni := map[string]struct{}{}
+	seenSHAs := map[string]struct{}{}
+	for _, path := range paths {
+		usersIn, err := readTimesFile(path)
+		if err != nil {
+			return err
+		}
+		f, err := readIntermediate(path)
+		if err != nil {
+			return err
+		}
+		for login, email := range f.Emails {
+			if emails == nil {
+				emails = map[string]string{}
+			}
+			emails[login] = email
+		}
+		for login, fileCommits := range f.Commits {
+			if commits == nil {
+				commits = map[string][]intermediateCommit{}
+			}
+			for _, c := range fileCommits {
+				if _, ok := seenSHAs[c.SHA]; !ok {
+					seenSHAs[c.SHA] = struct{}{}
+					commits[login] = append(commits[login], c)
+				}
+			}
+		}
+		for _, login := range f.Alumni {
+			alumni[login] = struct{}{}
+		}
+		for login, profile := range f.Profiles {
+			if profiles == nil {
+				profiles = map[string]intermediateProfile{}
+			}
+			profiles[login] = profile
+		}
+		fileCounts := map[string]map[string]int{}
+		for login, times := range usersIn {
+			if fileCounts[login] == nil {
+				fileCounts[login] = map[string]int{}
+			}
+			for _, t := range times {
+				fileCounts[login][t.Format(time.RFC3339)]++
+			}
+		}
+		for login, times := range fileCounts {
+			if counts[login] == nil {
+				counts[login] = map[string]int{}
+			}
+			for t, count := range times {
+				if count > counts[login][t] {
+					counts[login][t] = count
+				}
+			}
+		}
+	}
+
+	merged := map[string][]time.Time{}
+	for login, times := range counts {
+		for tIn, count := range times {
+			t, err := time.Parse(time.RFC3339, tIn)
+			if err != nil {
+				return err
+			}
+			for i := 0; i < count; i++ {
+				merged[login] = append(merged[login], t)
+			}
+		}
+	}
+	var alumniLogins []string
+	for login := range alumni {
+		alumniLogins = append(alumniLogins, login)
+	}
+	sort.Strings(alumniLogins)
+	return writeIntermediate(
+		outPath,
+		merged,
+		intermediateFile{Emails: emails, Profiles: profiles, Commits: commits, Alumni: alumniLogins},
+	)
+}
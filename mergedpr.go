@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// requireMergedPR drops the contributions of users who have never had a
+// pull request merged in the organization, e.g. commits pushed to branches
+// which were never accepted. It makes a search request per user, waiting
+// out the search rate limit as needed. Unlinked authors cannot be searched
+// for, so are dropped.
+func (s *scanner) requireMergedPR(ctx context.Context, ghClient *github.Client) error {
+	logins := make([]string, 0, len(s.userTimes))
+	for login := range s.userTimes {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	drop := map[string]struct{}{}
+	for _, login := range logins {
+		if !isUnlinkedLogin(login) {
+			query := fmt.Sprintf("is:pr is:merged org:%s author:%s", s.org, login)
+			var result *github.IssuesSearchResult
+			for {
+				var err error
+				result, _, err = ghClient.Search.Issues(
+					ctx,
+					query,
+					&github.SearchOptions{ListOptions: github.ListOptions{PerPage: 1}},
+				)
+				if err == nil {
+					break
+				}
+				if retry, err := waitForRateLimit(ctx, err); err != nil {
+					return err
+				} else if retry {
+					continue
+				}
+				return errors.Wrapf(err, "error searching merged pull requests by %s", login)
+			}
+			if result.GetTotal() > 0 {
+				continue
+			}
+		}
+		fmt.Printf("* excluding %s, who has no merged pull requests\n", login)
+		drop[login] = struct{}{}
+		s.excluded[reasonNoMergedPR] += len(s.userTimes[login])
+		delete(s.userTimes, login)
+		delete(s.users, login)
+	}
+	contributions := s.contributions[:0]
+	for _, c := range s.contributions {
+		if _, ok := drop[c.login]; !ok {
+			contributions = append(contributions, c)
+		}
+	}
+	s.contributions = contributions
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// outputFormatsByExtension maps the extensions of -outputs to the format
+// each is rendered in.
+var outputFormatsByExtension = map[string]string{
+	".md":       "markdown",
+	".markdown": "markdown",
+	".json":     "json",
+	".jsonl":    "jsonl",
+	".html":     "html",
+	".ics":      "ics",
+}
+
+type outputTarget struct {
+	path   string
+	format string
+}
+
+// outputTargets returns the files to write and the format of each: those
+// in -outputs if set, otherwise -output in -format.
+func outputTargets() ([]outputTarget, error) {
+	if *flagOutputs == "" {
+		return []outputTarget{{path: *flagOutput, format: *flagFormat}}, nil
+	}
+	var targets []outputTarget
+	for _, path := range strings.Split(*flagOutputs, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		format, ok := outputFormatsByExtension[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			var known []string
+			for ext := range outputFormatsByExtension {
+				known = append(known, ext)
+			}
+			sort.Strings(known)
+			return nil, errors.Newf(
+				"cannot infer the format of output %s; known extensions are %s",
+				path,
+				strings.Join(known, ", "),
+			)
+		}
+		targets = append(targets, outputTarget{path: path, format: format})
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("-outputs lists no files")
+	}
+	return targets, nil
+}
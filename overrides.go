@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/cockroachdb/errors"
+)
+
+// override replaces the name and/or URL displayed for a contributor.
+type override struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// readOverrides reads a JSON file mapping logins to overrides, e.g.
+//
+//	{"somelogin": {"name": "Some Name", "url": "https://example.com"}}
+func readOverrides(path string) (map[string]override, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overrides map[string]override
+	if err := json.Unmarshal(b, &overrides); err != nil {
+		return nil, errors.Wrapf(err, "error parsing overrides %s", path)
+	}
+	return overrides, nil
+}
+
+// applyOverrides replaces the name and URL of any user with an override.
+// Empty override fields leave the looked up value in place.
+func applyOverrides(users map[string]user, overrides map[string]override) {
+	for login, o := range overrides {
+		u, ok := users[login]
+		if !ok {
+			continue
+		}
+		if o.Name != "" {
+			u.name = o.Name
+		}
+		if o.URL != "" {
+			u.userURL = o.URL
+		}
+		users[login] = u
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+	"golang.org/x/sync/errgroup"
+)
+
+// scanRepoActivity scans the commits in repo and any other activity in it
+// which is being counted.
+func (s *scanner) scanRepoActivity(ctx context.Context, ghClient *github.Client, repo string) error {
+	if err := s.scanRepo(ctx, ghClient, repo); err != nil {
+		return err
+	}
+	if *flagScanForks {
+		if err := s.scanForks(ctx, ghClient, repo); err != nil {
+			return err
+		}
+	}
+	if *flagAttemptedContributors || *flagCountMergedPRs {
+		if err := s.scanPullRequests(ctx, ghClient, repo); err != nil {
+			return err
+		}
+	}
+	if *flagCountReviews {
+		if err := s.scanReviews(ctx, ghClient, repo); err != nil {
+			return err
+		}
+	}
+	if *flagCountDiscussions {
+		if err := s.scanDiscussions(ctx, ghClient, repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanRepos scans up to parallelism repos at a time, each with a context
+// derived from ctx. If failFast is set, the first error cancels the scans
+// still running and is returned; otherwise every repo is scanned and all
+// errors are returned together.
+func (s *scanner) scanRepos(
+	ctx context.Context, ghClient *github.Client, repos []string, parallelism int, failFast bool,
+) error {
+	g := &errgroup.Group{}
+	gCtx := ctx
+	if failFast {
+		g, gCtx = errgroup.WithContext(ctx)
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	g.SetLimit(parallelism)
+
+	var mu sync.Mutex
+	var errs error
+	for _, repo := range repos {
+		repo := repo
+		g.Go(func() error {
+			if err := gCtx.Err(); err != nil {
+				return err
+			}
+			err := s.scanRepoActivity(gCtx, ghClient, repo)
+			if err == nil || failFast {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			errs = errors.CombineErrors(errs, errors.Wrapf(err, "error scanning %s", repo))
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return errs
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// pathMatches returns whether file matches pattern, using path.Match
+// syntax. A pattern also matches every file underneath a directory it
+// matches (so "vendor" or "vendor/*" both match "vendor/a/b.go"), and a
+// pattern without a "/" matches against the base name (so "*.pb.go"
+// matches "pkg/a.pb.go").
+func pathMatches(pattern string, file string) bool {
+	if ok, _ := path.Match(pattern, file); ok {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+	}
+	for dir := path.Dir(file); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if ok, _ := path.Match(pattern, dir); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyTouchesExcludedPaths returns whether every file changed by the commit
+// matches one of the patterns. This fetches the commit, as commit listings
+// do not include the changed files.
+func onlyTouchesExcludedPaths(
+	ctx context.Context,
+	ghClient *github.Client,
+	org string,
+	repo string,
+	sha string,
+	patterns []string,
+) (bool, error) {
+	var commit *github.RepositoryCommit
+	for {
+		var err error
+		commit, _, err = ghClient.Repositories.GetCommit(ctx, org, repo, sha)
+		if err == nil {
+			break
+		}
+		if retry, err := waitForRateLimit(ctx, err); err != nil {
+			return false, err
+		} else if retry {
+			continue
+		}
+		return false, errors.Wrapf(err, "error getting commit %s in %s", sha, repo)
+	}
+	if len(commit.Files) == 0 {
+		return false, nil
+	}
+	for _, file := range commit.Files {
+		matched := false
+		for _, pattern := range patterns {
+			if pathMatches(pattern, file.GetFilename()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
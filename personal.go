@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// formatPersonalReport renders the contribution history of a single user
+// between start and end as Markdown. The repo breakdown and commit links
+// need the commits recorded by -include_commit_links.
+func formatPersonalReport(
+	u user, commits []intermediateCommit, start time.Time, end time.Time, generatedAt string,
+) (string, error) {
+	var times []time.Time
+	for _, t := range u.times {
+		if t.After(start) && t.Before(end) {
+			times = append(times, t)
+		}
+	}
+	if len(times) == 0 {
+		return "", errors.Newf("%s has no recorded contributions", u.login)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	name := escapeMarkdown(u.name)
+	if u.userURL != "" {
+		name = fmt.Sprintf("[%s](%s)", name, u.userURL)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Contribution History - %s\n\nLast generated at %s.\n\n", name, generatedAt)
+	fmt.Fprintf(
+		&b,
+		"%s commits, from %s to %s.\n\n",
+		formatCount(len(times)),
+		times[0].Format("2006-01-02"),
+		times[len(times)-1].Format("2006-01-02"),
+	)
+
+	byYear := map[int]int{}
+	for _, t := range times {
+		byYear[t.Year()]++
+	}
+	b.WriteString("## By Year\n\n")
+	for _, year := range reportYears(start, end) {
+		if byYear[year] > 0 {
+			fmt.Fprintf(&b, "* %d: %s\n", year, formatCount(byYear[year]))
+		}
+	}
+
+	var inRange []intermediateCommit
+	for _, commit := range commits {
+		if commit.Time.After(start) && commit.Time.Before(end) {
+			inRange = append(inRange, commit)
+		}
+	}
+	if len(inRange) == 0 {
+		return b.String(), nil
+	}
+	byRepo := map[string]int{}
+	for _, commit := range inRange {
+		byRepo[commit.Repo]++
+	}
+	repos := make([]string, 0, len(byRepo))
+	for repo := range byRepo {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if byRepo[repos[i]] == byRepo[repos[j]] {
+			return repos[i] < repos[j]
+		}
+		return byRepo[repos[i]] > byRepo[repos[j]]
+	})
+	b.WriteString("\n## By Repo\n\n")
+	for _, repo := range repos {
+		fmt.Fprintf(&b, "* %s: %s\n", repo, formatCount(byRepo[repo]))
+	}
+
+	sort.Slice(inRange, func(i, j int) bool { return inRange[i].Time.Before(inRange[j].Time) })
+	b.WriteString("\n## Commits\n\n")
+	for _, commit := range inRange {
+		fmt.Fprintf(
+			&b,
+			"* %s [%s@%.7s](%s/%s/commit/%s)\n",
+			commit.Time.Format("2006-01-02"),
+			commit.Repo,
+			commit.SHA,
+			strings.TrimSuffix(*flagGithubBaseURL, "/"),
+			commit.Repo,
+			commit.SHA,
+		)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// cacheProfiles records the profiles of the looked up users in the
+// intermediate file at path, for -render_only to use.
+func cacheProfiles(path string, looked []user, blocklistedNames nameBlocklist) error {
+	f, err := readIntermediate(path)
+	if err != nil {
+		return err
+	}
+	userTimes, err := readTimesFile(path)
+	if err != nil {
+		return err
+	}
+	profiles := map[string]intermediateProfile{}
+	for _, u := range looked {
+		_, inAuthors := blocklistedNames.authorsNames[u.name]
+		_, memberLogin := blocklistedNames.memberLogins[u.name]
+		profiles[u.login] = intermediateProfile{
+			Name:        u.name,
+			URL:         u.userURL,
+			AvatarURL:   u.avatarURL,
+			Company:     u.company,
+			Location:    u.location,
+			InAuthors:   inAuthors,
+			MemberLogin: memberLogin,
+		}
+	}
+	f.Profiles = profiles
+	return writeIntermediate(path, userTimes, f)
+}
+
+// cachedUsers returns the users in usersIn with the profiles cached by
+// cacheProfiles, in place of looking them up.
+func cachedUsers(
+	usersIn map[string][]time.Time, profiles map[string]intermediateProfile,
+) ([]user, error) {
+	var ret []user
+	for login, times := range usersIn {
+		profile, ok := profiles[login]
+		if !ok {
+			return nil, errors.Newf(
+				"no cached profile for %s in %s; run once without -render_only to cache profiles",
+				login,
+				*flagIntermediateOutput,
+			)
+		}
+		ret = append(ret, user{
+			userURL:   profile.URL,
+			avatarURL: profile.AvatarURL,
+			login:     login,
+			name:      profile.Name,
+			company:   profile.Company,
+			location:  profile.Location,
+			times:     times,
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// progressEvent is a machine readable progress event, per -progress_json.
+type progressEvent struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Repo  string    `json:"repo,omitempty"`
+	Count int       `json:"count,omitempty"`
+}
+
+// Values of progressEvent.Event.
+const (
+	progressRepoStarted    = "repo_started"
+	progressCommitsScanned = "commits_scanned"
+	progressRepoFinished   = "repo_finished"
+	progressLookupStarted  = "lookup_started"
+	progressLookupFinished = "lookup_finished"
+	progressDone           = "done"
+	progressFailed         = "failed"
+)
+
+// progressWriter writes progress events as newline delimited JSON. The
+// zero value discards them.
+type progressWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var progress = &progressWriter{}
+
+// open sends events to stderr if path is "-", and otherwise to path, which
+// may be a named pipe. It returns a function closing path.
+func (p *progressWriter) open(path string) (func(), error) {
+	if path == "-" {
+		p.w = os.Stderr
+		return func() {}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening %s", path)
+	}
+	p.w = f
+	return func() { _ = f.Close() }, nil
+}
+
+func (p *progressWriter) emit(e progressEvent) {
+	if p.w == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	b, err := json.Marshal(e)
+	if err != nil {
+		panic(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Progress is best effort, e.g. the reader of a pipe may have gone.
+	_, _ = p.w.Write(append(b, '\n'))
+}
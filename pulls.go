@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// scanPullRequests records the external authors of pull requests created in
+// the given repo within the scan window: of open or closed but unmerged
+// ones with -attempted_contributors, and of merged ones with
+// -count_merged_prs.
+func (s *scanner) scanPullRequests(
+	ctx context.Context, ghClient *github.Client, repo string,
+) error {
+	fmt.Printf("* Looking at pull requests for repo %s\n", repo)
+	opts := &github.PullRequestListOptions{
+		State:     "all",
+		Sort:      "created",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	more := true
+	for more {
+		prs, resp, err := ghClient.PullRequests.List(
+			ctx,
+			s.org,
+			repo,
+			opts,
+		)
+		if err != nil {
+			return errors.Wrapf(err, "error listing pull requests for %s", repo)
+		}
+		for _, pr := range prs {
+			created := pr.GetCreatedAt()
+			if created.Before(s.start) {
+				// Pull requests are listed newest first, so the rest are all
+				// outside the window too.
+				return nil
+			}
+			if created.After(s.end) {
+				continue
+			}
+			merged := pr.MergedAt != nil
+			if (merged && !*flagCountMergedPRs) || (!merged && !*flagAttemptedContributors) {
+				continue
+			}
+			login := pr.GetUser().GetLogin()
+			if !s.isExternalLogin(login) {
+				continue
+			}
+			s.mu.Lock()
+			if merged {
+				s.mergedPRs[login] = append(s.mergedPRs[login], created)
+			} else {
+				s.attempted[login] = append(s.attempted[login], created)
+			}
+			s.mu.Unlock()
+		}
+		more = resp.NextPage != 0
+		if more {
+			opts.Page = resp.NextPage
+		}
+	}
+	return nil
+}
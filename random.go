@@ -0,0 +1,23 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// lockedRand is a pseudo-random source which is safe for concurrent use,
+// so a single seeded source can drive all randomized behavior in a run.
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+func (r *lockedRand) int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Int63n(n)
+}
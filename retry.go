@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// maxAbuseRetries is the number of times a request is retried after
+// hitting GitHub's secondary (abuse) rate limit.
+const maxAbuseRetries = 5
+
+// serverErrorBackoff is the wait before the first retry of a request
+// which failed with a server error, doubling with every retry.
+const serverErrorBackoff = time.Second
+
+// retryTransport retries requests which hit GitHub's secondary rate limit,
+// waiting for exactly as long as the Retry-After header asks, and GET and
+// HEAD requests which failed with a transient server error, up to
+// -max_retries times with exponential backoff. Other requests failing with
+// a server error may have taken effect, e.g. creating a gist, so are not
+// retried. Other responses are returned as is.
+type retryTransport struct {
+	base http.RoundTripper
+	rand *lockedRand
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	abuseAttempts, serverErrorAttempts := 0, 0
+	for {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return resp, err
+		}
+		var wait time.Duration
+		switch {
+		case resp.StatusCode >= http.StatusInternalServerError &&
+			(req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+			serverErrorAttempts < *flagMaxRetries:
+			// Jitter spreads out the retries of concurrent requests which
+			// failed together.
+			backoff := serverErrorBackoff << uint(serverErrorAttempts)
+			wait = backoff + time.Duration(t.rand.int63n(int64(backoff)))
+			serverErrorAttempts++
+			fmt.Printf("* %s for %s, retrying in %s\n", resp.Status, req.URL, wait)
+		case resp.StatusCode == http.StatusForbidden && abuseAttempts < maxAbuseRetries:
+			retryAfter, err := abuseRetryAfter(resp)
+			if err != nil {
+				return nil, err
+			}
+			if retryAfter == nil {
+				return resp, nil
+			}
+			wait = *retryAfter
+			abuseAttempts++
+			fmt.Printf("* secondary rate limit hit for %s, retrying in %s\n", req.URL, wait)
+		default:
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// abuseRetryAfter returns how long to wait before retrying if resp is a
+// secondary rate limit error which says so, or nil otherwise. The response
+// body is left readable.
+func abuseRetryAfter(resp *http.Response) (*time.Duration, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// CheckResponse consumes the body, so give it a copy.
+	checkResp := *resp
+	checkResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(github.CheckResponse(&checkResp), &abuseErr) {
+		return abuseErr.RetryAfter, nil
+	}
+	return nil, nil
+}
+
+// waitForRateLimit waits for the rate limit to reset if err is a rate
+// limit error, returning whether the request should be retried.
+func waitForRateLimit(ctx context.Context, err error) (bool, error) {
+	var rateLimitErr *github.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return false, nil
+	}
+	wait := time.Until(rateLimitErr.Rate.Reset.Time) + time.Second
+	fmt.Printf("* rate limit hit, waiting %s\n", wait)
+	select {
+	case <-time.After(wait):
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
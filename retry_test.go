@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// sequenceTransport returns a transport responding with each of the given
+// responses in turn, and a pointer to the number of requests made.
+func sequenceTransport(responses ...*http.Response) (http.RoundTripper, *int) {
+	calls := 0
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := responses[calls]
+		resp.Request = req
+		calls++
+		return resp, nil
+	}), &calls
+}
+
+func testResponse(status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestRetryTransportAbuseRateLimit(t *testing.T) {
+	base, calls := sequenceTransport(
+		testResponse(
+			http.StatusForbidden,
+			http.Header{"Retry-After": []string{"0"}},
+			`{"message":"You have triggered an abuse detection mechanism.","documentation_url":"https://developer.github.com/v3/#abuse-rate-limits"}`,
+		),
+		testResponse(http.StatusOK, nil, `{}`),
+	)
+	rt := &retryTransport{base: base, rand: newLockedRand(1)}
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/users/a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || *calls != 2 {
+		t.Errorf("expected a 200 after 2 requests, got %d after %d", resp.StatusCode, *calls)
+	}
+}
+
+func TestRetryTransportForbiddenNotRetried(t *testing.T) {
+	const body = `{"message":"Must have admin rights to Repository."}`
+	base, calls := sequenceTransport(testResponse(http.StatusForbidden, nil, body))
+	rt := &retryTransport{base: base, rand: newLockedRand(1)}
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/a/b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden || *calls != 1 {
+		t.Errorf("expected a 403 after 1 request, got %d after %d", resp.StatusCode, *calls)
+	}
+	// The body is left readable for the caller.
+	if b, err := ioutil.ReadAll(resp.Body); err != nil || string(b) != body {
+		t.Errorf("expected body %q, got %q (%v)", body, b, err)
+	}
+}
+
+func TestRetryTransportServerError(t *testing.T) {
+	for _, tc := range []struct {
+		method        string
+		expectedCalls int
+		expected      int
+	}{
+		{method: http.MethodGet, expectedCalls: 2, expected: http.StatusOK},
+		// Retrying could, e.g., create a gist twice.
+		{method: http.MethodPost, expectedCalls: 1, expected: http.StatusBadGateway},
+	} {
+		t.Run(tc.method, func(t *testing.T) {
+			base, calls := sequenceTransport(
+				testResponse(http.StatusBadGateway, nil, ""),
+				testResponse(http.StatusOK, nil, `{}`),
+			)
+			rt := &retryTransport{base: base, rand: newLockedRand(1)}
+			req, err := http.NewRequest(tc.method, "https://api.github.com/gists", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tc.expected || *calls != tc.expectedCalls {
+				t.Errorf(
+					"expected a %d after %d requests, got %d after %d",
+					tc.expected,
+					tc.expectedCalls,
+					resp.StatusCode,
+					*calls,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// scanReviews records the approving and commenting reviews by external
+// contributors on pull requests in the given repo, bucketed by when the
+// review was submitted. This makes a request for every pull request updated
+// within the scan window, so waits out the rate limit rather than failing.
+func (s *scanner) scanReviews(ctx context.Context, ghClient *github.Client, repo string) error {
+	fmt.Printf("* Looking at reviews for repo %s\n", repo)
+	opts := &github.PullRequestListOptions{
+		State:     "all",
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	more := true
+	for more {
+		prs, resp, err := ghClient.PullRequests.List(
+			ctx,
+			s.org,
+			repo,
+			opts,
+		)
+		if err != nil {
+			if retry, err := waitForRateLimit(ctx, err); err != nil {
+				return err
+			} else if retry {
+				continue
+			}
+			return errors.Wrapf(err, "error listing pull requests for %s", repo)
+		}
+		for _, pr := range prs {
+			// A review is never submitted after the pull request was last
+			// updated, and pull requests are listed most recently updated
+			// first, so the rest can have no reviews within the window.
+			if pr.GetUpdatedAt().Before(s.start) {
+				return nil
+			}
+			if err := s.scanPullRequestReviews(ctx, ghClient, repo, pr.GetNumber()); err != nil {
+				return err
+			}
+		}
+		more = resp.NextPage != 0
+		if more {
+			opts.Page = resp.NextPage
+		}
+	}
+	return nil
+}
+
+func (s *scanner) scanPullRequestReviews(
+	ctx context.Context, ghClient *github.Client, repo string, number int,
+) error {
+	opts := &github.ListOptions{PerPage: 100}
+	more := true
+	for more {
+		reviews, resp, err := ghClient.PullRequests.ListReviews(
+			ctx,
+			s.org,
+			repo,
+			number,
+			opts,
+		)
+		if err != nil {
+			if retry, err := waitForRateLimit(ctx, err); err != nil {
+				return err
+			} else if retry {
+				continue
+			}
+			return errors.Wrapf(err, "error listing reviews for %s#%d", repo, number)
+		}
+		for _, review := range reviews {
+			if review.GetState() != "APPROVED" && review.GetState() != "COMMENTED" {
+				continue
+			}
+			submitted := review.GetSubmittedAt()
+			if submitted.Before(s.start) || submitted.After(s.end) {
+				continue
+			}
+			login := review.GetUser().GetLogin()
+			if !s.isExternalLogin(login) {
+				continue
+			}
+			s.mu.Lock()
+			s.reviews[login] = append(s.reviews[login], submitted)
+			s.mu.Unlock()
+		}
+		more = resp.NextPage != 0
+		if more {
+			opts.Page = resp.NextPage
+		}
+	}
+	return nil
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// scanner accumulates external contributions from commits, filtering out
+// anything attributable to the organization.
+type scanner struct {
+	// org is the organization whose repos are scanned.
+	org   string
+	start time.Time
+	end   time.Time
+
+	organizationMembers map[string]*github.User
+	emails              map[string]struct{}
+	names               map[string]struct{}
+	blocklist           loginPatterns
+	allowlist           loginPatterns
+	// excludePaths, if set, excludes commits which only change files
+	// matching these patterns.
+	excludePaths []string
+	// internalLogins are always excluded, even if allowlisted.
+	internalLogins map[string]struct{}
+	// excludedEmails are lower cased commit author emails to exclude.
+	excludedEmails map[string]struct{}
+	// authorsLogins are the lower cased logins annotated on the
+	// organization's AUTHORS entries.
+	authorsLogins map[string]struct{}
+
+	users     map[string]*github.User
+	userTimes map[string][]time.Time
+	// Commits are keyed by SHA so a commit seen twice (e.g. a repo listed
+	// twice, or pages shifting under us mid-scan) is only counted once.
+	seenSHAs      map[string]struct{}
+	contributions []contribution
+	// attempted holds the creation times of unmerged pull requests by
+	// external authors.
+	attempted map[string][]time.Time
+	// mergedPRs holds the creation times of merged pull requests by
+	// external authors.
+	mergedPRs map[string][]time.Time
+	// reviews holds the submission times of reviews by external
+	// contributors.
+	reviews map[string][]time.Time
+	// discussions holds the creation times of discussions and discussion
+	// comments by external contributors.
+	discussions map[string][]time.Time
+	// excluded counts the commits skipped, by the reason they were skipped.
+	excluded map[string]int
+	// blocklistHits counts the commits excluded by each blocklist entry.
+	blocklistHits map[string]int
+	// alumni holds the logins of org members and AUTHORS entries whose
+	// commits were counted per -annotate_alumni.
+	alumni map[string]struct{}
+	// nameCollisions holds the logins warned about by warnNameCollision.
+	nameCollisions map[string]struct{}
+	// pullAuthors caches the authors of pull requests looked up by
+	// squashAuthor, keyed by repo#number.
+	pullAuthors map[string]*github.User
+	// mailmaps caches the .mailmap of each repo, per -use_mailmap.
+	mailmaps map[string]*mailmap
+
+	checkpointPath string
+	checkpoint     checkpoint
+	// checkpointed is how many of contributions have been written to the
+	// checkpoint.
+	checkpointed int
+
+	// mu guards the accumulated state when repos are scanned in parallel.
+	mu sync.Mutex
+}
+
+func newScanner(
+	org string,
+	start time.Time,
+	end time.Time,
+	organizationMembers map[string]*github.User,
+	emails map[string]struct{},
+	names map[string]struct{},
+	blocklist loginPatterns,
+	allowlist loginPatterns,
+) *scanner {
+	return &scanner{
+		org:                 org,
+		start:               start,
+		end:                 end,
+		organizationMembers: organizationMembers,
+		emails:              emails,
+		names:               names,
+		blocklist:           blocklist,
+		allowlist:           allowlist,
+		users:               map[string]*github.User{},
+		userTimes:           map[string][]time.Time{},
+		seenSHAs:            map[string]struct{}{},
+		excluded:            map[string]int{},
+		blocklistHits:       map[string]int{},
+		alumni:              map[string]struct{}{},
+		nameCollisions:      map[string]struct{}{},
+		pullAuthors:         map[string]*github.User{},
+		mailmaps:            map[string]*mailmap{},
+		attempted:           map[string][]time.Time{},
+		mergedPRs:           map[string][]time.Time{},
+		reviews:             map[string][]time.Time{},
+		discussions:         map[string][]time.Time{},
+	}
+}
+
+// Reasons a commit is not counted as an external contribution.
+const (
+	reasonOutsideDateRange  = "outside date range"
+	reasonMissingDate       = "missing dates"
+	reasonMergeCommit       = "merge commits"
+	reasonUnlinkedAuthor    = "unlinked authors"
+	reasonOrgMember         = "org members"
+	reasonBlocklist         = "blocklist"
+	reasonOrganizationEmail = "cockroachlabs emails"
+	reasonMergeMessage      = "merge messages"
+	reasonAuthorsName       = "AUTHORS names"
+	reasonAuthorsEmail      = "AUTHORS emails"
+	reasonAuthorsLogin      = "AUTHORS logins"
+	reasonDuplicate         = "duplicates"
+	reasonExcludedPaths     = "excluded paths"
+	reasonExcludedBranch    = "excluded branches"
+	reasonInternalLogin     = "internal logins"
+	reasonExcludedEmail     = "excluded emails"
+	reasonNoMergedPR        = "no merged pull requests"
+)
+
+// commitLogin returns the login the commit is attributed to, or "" if the
+// commit cannot be attributed to anyone.
+func commitLogin(commit *github.RepositoryCommit) string {
+	login := commit.GetAuthor().GetLogin()
+	if login == "" && *flagResolveUnlinked {
+		if email := commit.GetCommit().GetAuthor().GetEmail(); email != "" {
+			login = unlinkedLogin(email)
+		}
+	}
+	return login
+}
+
+// isExternalLogin returns whether activity other than commits, such as
+// reviews, by the given login counts as external.
+func (s *scanner) isExternalLogin(login string) bool {
+	return s.loginExclusion(login) == ""
+}
+
+// loginExclusion returns why activity by the given login is not external,
+// or "" if it is.
+func (s *scanner) loginExclusion(login string) string {
+	if login == "" {
+		return reasonUnlinkedAuthor
+	}
+	if _, ok := s.internalLogins[login]; ok {
+		return reasonInternalLogin
+	}
+	if s.allowlist.matches(login) {
+		return ""
+	}
+	if _, ok := s.organizationMembers[login]; ok {
+		return reasonOrgMember
+	}
+	if _, ok := s.authorsLogins[strings.ToLower(login)]; ok && !*flagNoAuthorsFilter {
+		return reasonAuthorsLogin
+	}
+	if s.blocklist.matches(login) {
+		return reasonBlocklist
+	}
+	return ""
+}
+
+// isExternal returns whether the commit was made by an external contributor,
+// and if not, the reason why. With -annotate_alumni, commits by org members
+// and AUTHORS entries are counted unless made with an organization email,
+// and their authors are recorded as alumni.
+func (s *scanner) isExternal(commit *github.RepositoryCommit) (bool, string) {
+	if len(commit.GetCommit().Parents) > 0 {
+		return false, reasonMergeCommit
+	}
+	login := commitLogin(commit)
+	if login == "" {
+		return false, reasonUnlinkedAuthor
+	}
+	if _, ok := s.internalLogins[login]; ok {
+		return false, reasonInternalLogin
+	}
+	if s.allowlist.matches(login) {
+		return true, ""
+	}
+	alumni := false
+	if _, ok := s.organizationMembers[login]; ok {
+		if !*flagAnnotateAlumni {
+			return false, reasonOrgMember
+		}
+		alumni = true
+	}
+	if entry, ok := s.blocklist.match(login); ok {
+		s.blocklistHits[entry]++
+		return false, reasonBlocklist
+	}
+	if !*flagNoEmailFilter &&
+		strings.Contains(commit.GetCommit().GetAuthor().GetEmail(), "@cockroachlabs.com") {
+		return false, reasonOrganizationEmail
+	}
+	if _, ok := s.excludedEmails[strings.ToLower(commit.GetCommit().GetAuthor().GetEmail())]; ok {
+		return false, reasonExcludedEmail
+	}
+	if strings.HasPrefix(commit.GetCommit().GetMessage(), "Merge pull request ") {
+		return false, reasonMergeMessage
+	}
+	if reason := s.authorsExclusion(commit, login); reason != "" {
+		if !*flagAnnotateAlumni {
+			return false, reason
+		}
+		alumni = true
+	}
+	if alumni {
+		s.alumni[login] = struct{}{}
+	}
+	return true, ""
+}
+
+// authorsExclusion returns why the commit is attributed to an AUTHORS
+// entry, or "" if it is not.
+func (s *scanner) authorsExclusion(commit *github.RepositoryCommit, login string) string {
+	if *flagNoAuthorsFilter {
+		return ""
+	}
+	if _, ok := s.authorsLogins[strings.ToLower(login)]; ok {
+		return reasonAuthorsLogin
+	}
+	if _, ok := s.names[commit.GetAuthor().GetName()]; ok {
+		if _, ok := s.emails[commit.GetCommit().GetAuthor().GetEmail()]; !ok && !*flagAnnotateAlumni {
+			s.warnNameCollision(login, commit.GetAuthor().GetName())
+		}
+		return reasonAuthorsName
+	}
+	if _, ok := s.emails[commit.GetCommit().GetAuthor().GetEmail()]; ok {
+		return reasonAuthorsEmail
+	}
+	return ""
+}
+
+// processCommit records the commit if it was made by an external
+// contributor. It is safe to call concurrently.
+func (s *scanner) processCommit(
+	ctx context.Context, ghClient *github.Client, repo string, commit *github.RepositoryCommit,
+) error {
+	if *flagResolveSquashAuthors {
+		var err error
+		if commit, err = s.squashAuthor(ctx, ghClient, repo, commit); err != nil {
+			return err
+		}
+	}
+	if *flagUseMailmap {
+		var err error
+		if commit, err = s.applyMailmap(ctx, ghClient, repo, commit); err != nil {
+			return err
+		}
+	}
+	credited := s.checkCommit(commit)
+	if len(credited) == 0 {
+		return nil
+	}
+	if len(s.excludePaths) > 0 {
+		excluded, err := onlyTouchesExcludedPaths(ctx, ghClient, s.org, repo, commit.GetSHA(), s.excludePaths)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.exclude(reasonExcludedPaths)
+			return nil
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, credit := range credited {
+		login := commitLogin(credit)
+		fmt.Printf(
+			"* found commit by %s (%s)) on %s\n",
+			login,
+			credit.GetCommit().GetAuthor().GetEmail(),
+			credit.Commit.GetAuthor().GetDate().Format(time.RFC3339),
+		)
+		s.users[login] = credit.GetAuthor()
+		s.userTimes[login] = append(
+			s.userTimes[login],
+			credit.Commit.GetAuthor().GetDate(),
+		)
+		s.contributions = append(s.contributions, contribution{
+			login: login,
+			name:  credit.GetCommit().GetAuthor().GetName(),
+			email: credit.GetCommit().GetAuthor().GetEmail(),
+			repo:  repo,
+			sha:   credit.GetSHA(),
+			t:     credit.GetCommit().GetAuthor().GetDate(),
+		})
+	}
+	return nil
+}
+
+// warnNameCollision warns, once per login, that the login's commits are
+// excluded only because the author's name matches a name in AUTHORS, which
+// may be a different person sharing the name.
+func (s *scanner) warnNameCollision(login string, name string) {
+	if _, ok := s.nameCollisions[login]; ok {
+		return
+	}
+	s.nameCollisions[login] = struct{}{}
+	fmt.Printf(
+		"* WARNING: excluding commits by %s only because their name %q is in AUTHORS; allowlist them if they are external\n",
+		login,
+		name,
+	)
+}
+
+// firstSeen returns whether no commit with the given SHA has been seen
+// before, marking it seen.
+func firstSeen(seenSHAs map[string]struct{}, sha string) bool {
+	if _, ok := seenSHAs[sha]; ok {
+		return false
+	}
+	seenSHAs[sha] = struct{}{}
+	return true
+}
+
+// checkCommit marks the commit seen and returns the commits to credit for
+// it: the commit itself if its author is external, and with -count_coauthors
+// a copy for each co-author to credit. Nothing is credited for a commit seen
+// before. It records why the commit's author is excluded, if they are.
+func (s *scanner) checkCommit(commit *github.RepositoryCommit) []*github.RepositoryCommit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !firstSeen(s.seenSHAs, commit.GetSHA()) {
+		s.exclude(reasonDuplicate)
+		return nil
+	}
+	d := commit.GetCommit().GetAuthor().GetDate()
+	if d.IsZero() {
+		fmt.Printf("* WARNING: skipping commit %s, which has no author date\n", commit.GetSHA())
+		s.exclude(reasonMissingDate)
+		return nil
+	}
+	if s.start.After(d) || d.After(s.end) {
+		s.exclude(reasonOutsideDateRange)
+		return nil
+	}
+	var credited []*github.RepositoryCommit
+	if external, reason := s.isExternal(commit); external {
+		credited = append(credited, commit)
+	} else {
+		s.exclude(reason)
+	}
+	if *flagCountCoauthors {
+		credited = append(credited, s.checkCoauthors(commit)...)
+	}
+	return credited
+}
+
+func (s *scanner) exclude(reason string) {
+	s.excluded[reason]++
+}
+
+// exclusionSummary describes how many commits were excluded by each filter,
+// most common first.
+func (s *scanner) exclusionSummary() string {
+	type reasonCount struct {
+		reason string
+		count  int
+	}
+	var counts []reasonCount
+	total := 0
+	for reason, count := range s.excluded {
+		counts = append(counts, reasonCount{reason: reason, count: count})
+		total += count
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].count == counts[j].count {
+			return counts[i].reason < counts[j].reason
+		}
+		return counts[i].count > counts[j].count
+	})
+	var parts []string
+	for _, c := range counts {
+		parts = append(parts, fmt.Sprintf("%d %s", c.count, c.reason))
+	}
+	return fmt.Sprintf("Excluded %d commits: %s", total, strings.Join(parts, ", "))
+}
+
+// alumniLogins returns the sorted logins of the alumni with counted
+// commits.
+func (s *scanner) alumniLogins() []string {
+	var logins []string
+	for login := range s.alumni {
+		if _, ok := s.userTimes[login]; ok {
+			logins = append(logins, login)
+		}
+	}
+	sort.Strings(logins)
+	return logins
+}
+
+// authorEmails returns the author email of each login's latest commit.
+func (s *scanner) authorEmails() map[string]string {
+	emails := map[string]string{}
+	latest := map[string]time.Time{}
+	for _, c := range s.contributions {
+		if c.email == "" || c.t.Before(latest[c.login]) {
+			continue
+		}
+		emails[c.login] = c.email
+		latest[c.login] = c.t
+	}
+	return emails
+}
+
+// commitsByLogin returns the commits counted for each login, with repos
+// qualified by the organization.
+func (s *scanner) commitsByLogin() map[string][]intermediateCommit {
+	commits := map[string][]intermediateCommit{}
+	for _, c := range s.contributions {
+		commits[c.login] = append(commits[c.login], intermediateCommit{
+			Repo: s.org + "/" + c.repo,
+			SHA:  c.sha,
+			Time: c.t,
+		})
+	}
+	return commits
+}
+
+// blocklistReport lists every blocklist entry with the number of commits it
+// excluded, so entries which never match can be pruned.
+func (s *scanner) blocklistReport() string {
+	var lines []string
+	for _, entry := range s.blocklist.entries {
+		lines = append(lines, fmt.Sprintf("%s: %d", entry, s.blocklistHits[entry]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// preview returns the top n contributors by login and commit count.
+func (s *scanner) preview(n int) string {
+	type loginCount struct {
+		login string
+		count int
+	}
+	var counts []loginCount
+	for login, times := range s.userTimes {
+		counts = append(counts, loginCount{login: login, count: len(times)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].count == counts[j].count {
+			return counts[i].login < counts[j].login
+		}
+		return counts[i].count > counts[j].count
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	var lines []string
+	for i, c := range counts {
+		lines = append(lines, fmt.Sprintf("%3d. %s (%d)", i+1, c.login, c.count))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scanRepo processes every commit in the given repo within the scan window.
+// Only the default branch is scanned. If it is in -exclude_branches, its
+// commits which are not also on another branch are skipped. If a checkpoint was loaded, completed repos are
+// skipped and the repo in progress is resumed from the page after the last
+// one processed; pages may have shifted since, but any commit seen twice is
+// deduplicated by SHA.
+func (s *scanner) scanRepo(ctx context.Context, ghClient *github.Client, repo string) error {
+	if s.repoCompleted(repo) {
+		fmt.Printf("* Skipping repo %s, already scanned according to checkpoint\n", repo)
+		return nil
+	}
+	opts := &github.CommitsListOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+		Since: s.start,
+	}
+	// Without an explicit end date the end is now, which would change the
+	// request URL every run and defeat any ETag caching; commits after the
+	// end are filtered out in processCommit regardless.
+	if *flagEndDate != "" {
+		opts.Until = s.end
+	}
+	// Only the default branch is scanned, so only an excluded default branch
+	// can have commits which are not on any allowed branch. If so, onAllowed
+	// holds the commits which are.
+	var onAllowed map[string]struct{}
+	if *flagExcludeBranches != "" {
+		r, _, err := ghClient.Repositories.Get(ctx, s.org, repo)
+		if err != nil {
+			return errors.Wrapf(markGithubError(err), "error getting repo %s", repo)
+		}
+		if isExcludedBranch(r.GetDefaultBranch()) {
+			fmt.Printf(
+				"* Default branch %s of repo %s is excluded, only counting commits also on other branches\n",
+				r.GetDefaultBranch(),
+				repo,
+			)
+			if onAllowed, err = allowedBranchCommits(ctx, ghClient, s.org, repo, *opts); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("* Looking at repo %s\n", repo)
+	progress.emit(progressEvent{Event: progressRepoStarted, Repo: repo})
+	if s.checkpoint.Repo == repo {
+		fmt.Printf("* Resuming repo %s from page %d\n", repo, s.checkpoint.NextPage)
+		opts.Page = s.checkpoint.NextPage
+	}
+	processed := 0
+	more := true
+	for more {
+		commits, resp, err := ghClient.Repositories.ListCommits(
+			ctx,
+			s.org,
+			repo,
+			opts,
+		)
+		if err != nil {
+			return errors.Wrapf(markGithubError(err), "error listing commits for %s", repo)
+		}
+		for _, commit := range commits {
+			if onAllowed != nil {
+				if _, ok := onAllowed[commit.GetSHA()]; !ok {
+					s.mu.Lock()
+					s.exclude(reasonExcludedBranch)
+					s.mu.Unlock()
+					continue
+				}
+			}
+			if *flagMaxCommitsPerRepo > 0 && processed >= *flagMaxCommitsPerRepo {
+				fmt.Printf("* Stopping repo %s after %d commits\n", repo, processed)
+				progress.emit(progressEvent{Event: progressRepoFinished, Repo: repo, Count: processed})
+				return s.saveCheckpoint(repo, 0)
+			}
+			processed++
+			if err := s.processCommit(ctx, ghClient, repo, commit); err != nil {
+				return err
+			}
+		}
+		if err := s.saveCheckpoint(repo, resp.NextPage); err != nil {
+			return err
+		}
+		progress.emit(progressEvent{Event: progressCommitsScanned, Repo: repo, Count: processed})
+		more = resp.NextPage != 0
+		if more {
+			opts.Page = resp.NextPage
+		}
+	}
+	progress.emit(progressEvent{Event: progressRepoFinished, Repo: repo, Count: processed})
+	return nil
+}
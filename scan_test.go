@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+)
+
+// testGithubClient returns a client which sends every request to handler.
+func testGithubClient(t *testing.T, handler http.Handler) *github.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	ghClient := github.NewClient(srv.Client())
+	baseURL, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ghClient.BaseURL = baseURL
+	return ghClient
+}
+
+func TestFirstSeen(t *testing.T) {
+	seenSHAs := map[string]struct{}{}
+	if !firstSeen(seenSHAs, "abc") {
+		t.Fatal("expected the first sighting of a commit to be counted")
+	}
+	if firstSeen(seenSHAs, "abc") {
+		t.Fatal("expected a duplicate commit not to be counted")
+	}
+	if !firstSeen(seenSHAs, "def") {
+		t.Fatal("expected a different commit to be counted")
+	}
+}
+
+func testScanner() *scanner {
+	return newScanner(
+		"cockroachdb",
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		map[string]*github.User{},
+		map[string]struct{}{},
+		map[string]struct{}{},
+		parseLoginPatterns(""),
+		parseLoginPatterns(""),
+	)
+}
+
+func testCommit(sha string, login string, email string, date time.Time) *github.RepositoryCommit {
+	return &github.RepositoryCommit{
+		SHA:    github.String(sha),
+		Author: &github.User{Login: github.String(login)},
+		Commit: &github.Commit{
+			Author: &github.CommitAuthor{
+				Name:  github.String(login),
+				Email: github.String(email),
+				Date:  &date,
+			},
+		},
+	}
+}
+
+func TestIsExternal(t *testing.T) {
+	s := newScanner(
+		"cockroachdb",
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		map[string]*github.User{"member": {Login: github.String("member")}},
+		map[string]struct{}{"former@example.com": {}},
+		map[string]struct{}{"Named Employee": {}},
+		parseLoginPatterns("blocked,*[bot]"),
+		parseLoginPatterns(""),
+	)
+	when := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	namedEmployee := testCommit("f", "named", "named@example.com", when)
+	namedEmployee.Author.Name = github.String("Named Employee")
+
+	for _, tc := range []struct {
+		name           string
+		commit         *github.RepositoryCommit
+		expected       bool
+		expectedReason string
+	}{
+		{
+			name:     "outsider",
+			commit:   testCommit("a", "outsider", "outsider@example.com", when),
+			expected: true,
+		},
+		{
+			name:           "org member",
+			commit:         testCommit("b", "member", "member@example.com", when),
+			expectedReason: reasonOrgMember,
+		},
+		{
+			name:           "blocklisted login",
+			commit:         testCommit("c", "blocked", "blocked@example.com", when),
+			expectedReason: reasonBlocklist,
+		},
+		{
+			name:           "blocklisted pattern",
+			commit:         testCommit("d", "renovate[bot]", "bot@example.com", when),
+			expectedReason: reasonBlocklist,
+		},
+		{
+			name:           "organization email",
+			commit:         testCommit("e", "employee", "employee@cockroachlabs.com", when),
+			expectedReason: reasonOrganizationEmail,
+		},
+		{
+			name:           "AUTHORS name",
+			commit:         namedEmployee,
+			expectedReason: reasonAuthorsName,
+		},
+		{
+			name:           "AUTHORS email",
+			commit:         testCommit("g", "former", "former@example.com", when),
+			expectedReason: reasonAuthorsEmail,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			external, reason := s.isExternal(tc.commit)
+			if external != tc.expected || reason != tc.expectedReason {
+				t.Errorf(
+					"isExternal() = (%t, %q), expected (%t, %q)",
+					external,
+					reason,
+					tc.expected,
+					tc.expectedReason,
+				)
+			}
+		})
+	}
+}
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	f()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestCheckCommitMissingDate(t *testing.T) {
+	s := testScanner()
+	var credited []*github.RepositoryCommit
+	out := captureStdout(t, func() {
+		credited = s.checkCommit(testCommit("abc", "outsider", "outsider@example.com", time.Time{}))
+	})
+	if len(credited) != 0 {
+		t.Error("expected a commit without an author date not to be counted")
+	}
+	if got := s.excluded[reasonMissingDate]; got != 1 {
+		t.Errorf("expected 1 commit excluded for a missing date, got %d", got)
+	}
+	if got := s.excluded[reasonOutsideDateRange]; got != 0 {
+		t.Errorf("expected no commits excluded as outside the date range, got %d", got)
+	}
+	if !strings.Contains(out, "skipping commit abc, which has no author date") {
+		t.Errorf("expected the skipped commit to be logged, got %q", out)
+	}
+}
+
+func TestScanRepoExcludedBranches(t *testing.T) {
+	*flagExcludeBranches = "gh-pages"
+	defer func() { *flagExcludeBranches = "" }()
+
+	commitJSON := func(sha string, login string) string {
+		return fmt.Sprintf(
+			`{"sha": %q, "author": {"login": %q}, "commit": {"author": {"email": "%s@example.com", "date": "2020-06-01T00:00:00Z"}}}`,
+			sha,
+			login,
+			login,
+		)
+	}
+	commitsByBranch := map[string][]string{
+		"main":     {commitJSON("shared", "alice"), commitJSON("feature", "bob")},
+		"gh-pages": {commitJSON("shared", "alice"), commitJSON("deploy", "carol")},
+	}
+	for _, tc := range []struct {
+		defaultBranch  string
+		expectedListed string
+		expected       string
+	}{
+		// Commits on an allowed default branch are all counted, without
+		// listing any other branch.
+		{defaultBranch: "main", expectedListed: "", expected: "  1. alice (1)\n  2. bob (1)"},
+		// Only the commits on an excluded default branch which are also on an
+		// allowed branch are counted. The allowed branch is only listed to
+		// check membership, so its own commits are not counted.
+		{defaultBranch: "gh-pages", expectedListed: "main", expected: "  1. alice (1)"},
+	} {
+		t.Run(tc.defaultBranch, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/cockroachdb/docs", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"default_branch": %q}`, tc.defaultBranch)
+			})
+			mux.HandleFunc("/repos/cockroachdb/docs/branches", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `[{"name": "gh-pages"}, {"name": "main"}]`)
+			})
+			var listed []string
+			mux.HandleFunc("/repos/cockroachdb/docs/commits", func(w http.ResponseWriter, r *http.Request) {
+				branch := r.URL.Query().Get("sha")
+				listed = append(listed, branch)
+				if branch == "" {
+					branch = tc.defaultBranch
+				}
+				fmt.Fprintf(w, "[%s]", strings.Join(commitsByBranch[branch], ","))
+			})
+
+			s := testScanner()
+			if err := s.scanRepo(context.Background(), testGithubClient(t, mux), "docs"); err != nil {
+				t.Fatal(err)
+			}
+			// The default branch is listed without naming it.
+			if got := strings.Join(listed[:len(listed)-1], ","); got != tc.expectedListed {
+				t.Errorf("expected %q to be listed before the default branch, got %q", tc.expectedListed, got)
+			}
+			if got := s.preview(10); got != tc.expected {
+				t.Errorf("unexpected contributors:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestScanRepoAnnotateAlumni(t *testing.T) {
+	commitJSON := func(sha string, login string, email string) string {
+		return fmt.Sprintf(
+			`{"sha": %q, "author": {"login": %q}, "commit": {"author": {"email": %q, "date": "2020-06-01T00:00:00Z"}}}`,
+			sha,
+			login,
+			email,
+		)
+	}
+	commits := []string{
+		commitJSON("a", "outsider", "outsider@example.com"),
+		commitJSON("b", "member", "member@example.com"),
+		commitJSON("c", "member", "member@cockroachlabs.com"),
+		commitJSON("d", "former", "former@example.com"),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/cockroachdb/docs/commits", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "[%s]", strings.Join(commits, ","))
+	})
+	ghClient := testGithubClient(t, mux)
+	scan := func() *scanner {
+		s := testScanner()
+		s.organizationMembers = map[string]*github.User{"member": {Login: github.String("member")}}
+		s.authorsLogins = map[string]struct{}{"former": {}}
+		if err := s.scanRepo(context.Background(), ghClient, "docs"); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := scan()
+	if got := s.preview(10); got != "  1. outsider (1)" {
+		t.Errorf("expected members and AUTHORS entries to be excluded, got:\n%s", got)
+	}
+
+	*flagAnnotateAlumni = true
+	defer func() { *flagAnnotateAlumni = false }()
+	s = scan()
+	// Commits made with an organization email are still excluded.
+	if got := s.preview(10); got != "  1. former (1)\n  2. member (1)\n  3. outsider (1)" {
+		t.Errorf("expected the members' external commits to be counted, got:\n%s", got)
+	}
+	if got := strings.Join(s.alumniLogins(), ","); got != "former,member" {
+		t.Errorf("expected former and member to be alumni, got %s", got)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// scanSearch processes every commit matched by the given commit search
+// query. The search API has a much lower rate limit than the rest of the
+// API (30 requests a minute), so when the limit is hit we wait for it to
+// reset rather than failing the scan. Note GitHub only ever returns the
+// first 1000 results of a search.
+func (s *scanner) scanSearch(ctx context.Context, ghClient *github.Client, query string) error {
+	if !strings.Contains(query, "org:") && !strings.Contains(query, "repo:") {
+		query = fmt.Sprintf("org:%s %s", *flagOrganization, query)
+	}
+	fmt.Printf("* Searching commits matching %q\n", query)
+	opts := &github.SearchOptions{
+		Sort: "author-date",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	more := true
+	for more {
+		result, resp, err := ghClient.Search.Commits(ctx, query, opts)
+		if err != nil {
+			if retry, err := waitForRateLimit(ctx, err); err != nil {
+				return err
+			} else if retry {
+				continue
+			}
+			return errors.Wrapf(err, "error searching commits for %q", query)
+		}
+		for _, r := range result.Commits {
+			if err := s.processCommit(
+				ctx,
+				ghClient,
+				r.GetRepository().GetName(),
+				&github.RepositoryCommit{
+					SHA:       r.SHA,
+					Commit:    r.Commit,
+					Author:    r.Author,
+					Committer: r.Committer,
+					HTMLURL:   r.HTMLURL,
+				},
+			); err != nil {
+				return err
+			}
+		}
+		more = resp.NextPage != 0
+		if more {
+			opts.Page = resp.NextPage
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+)
+
+// formatSocial renders a post thanking the contributors between start and
+// end by @handle, most contributions first, fitting in maxChars by
+// mentioning as many as fit and counting the rest. Unlinked authors have no
+// handle, so are only counted.
+func formatSocial(users map[string]user, start time.Time, end time.Time, maxChars int) (string, error) {
+	var handles []string
+	ranked := rankContributors(users, start, end)
+	for _, entry := range ranked {
+		if !isUnlinkedLogin(entry.u.login) {
+			handles = append(handles, "@"+entry.u.login)
+		}
+	}
+	const prefix = "Huge thanks to our external contributors: "
+	const suffix = " 🎉"
+	for n := len(handles); n >= 0; n-- {
+		post := prefix + strings.Join(handles[:n], " ")
+		if more := len(ranked) - n; more > 0 {
+			if n > 0 {
+				post += " and"
+			}
+			post += fmt.Sprintf(" %d more", more)
+		}
+		post += suffix
+		if utf8.RuneCountInString(post) <= maxChars {
+			return post, nil
+		}
+	}
+	return "", errors.Newf("-social_max_chars of %d is too short for a post", maxChars)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// lastContribution returns the time of the user's latest contribution.
+func lastContribution(u user) time.Time {
+	var last time.Time
+	for _, t := range u.times {
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// contributionSpan returns the time between the user's first and last
+// contributions.
+func contributionSpan(u user) time.Duration {
+	return lastContribution(u).Sub(firstContribution(u))
+}
+
+// formatSpan describes a contribution span, e.g. "2.3 years".
+func formatSpan(span time.Duration) string {
+	days := span.Hours() / 24
+	if days < 365 {
+		return fmt.Sprintf("%d days", int(days))
+	}
+	return fmt.Sprintf("%.1f years", days/365.25)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+	_ "modernc.org/sqlite" // registers the pure-Go "sqlite" driver
+)
+
+// writeSQLite writes the scanned contributors and their individual
+// contributions into a fresh SQLite database at path. Contributor names are
+// taken from the git author name, as commit listings do not include the
+// GitHub display name.
+func writeSQLite(
+	path string, users map[string]*github.User, contributions []contribution,
+) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %s", path)
+	}
+	defer func() { _ = db.Close() }()
+
+	for _, stmt := range []string{
+		`DROP TABLE IF EXISTS contributors`,
+		`DROP TABLE IF EXISTS contributions`,
+		`CREATE TABLE contributors (login TEXT PRIMARY KEY, name TEXT, url TEXT)`,
+		`CREATE TABLE contributions (login TEXT, repo TEXT, sha TEXT, ts TIMESTAMP, PRIMARY KEY (repo, sha, login))`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return errors.Wrapf(err, "error executing %q", stmt)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	names := map[string]string{}
+	for _, c := range contributions {
+		if _, ok := names[c.login]; !ok && c.name != "" {
+			names[c.login] = c.name
+		}
+	}
+	logins := make([]string, 0, len(users))
+	for login := range users {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	for _, login := range logins {
+		if _, err := tx.Exec(
+			`INSERT INTO contributors (login, name, url) VALUES (?, ?, ?)`,
+			login,
+			names[login],
+			users[login].GetHTMLURL(),
+		); err != nil {
+			return errors.Wrapf(err, "error inserting contributor %s", login)
+		}
+	}
+	for _, c := range contributions {
+		if _, err := tx.Exec(
+			`INSERT INTO contributions (login, repo, sha, ts) VALUES (?, ?, ?, ?)`,
+			c.login,
+			c.repo,
+			c.sha,
+			c.t.UTC().Format(time.RFC3339),
+		); err != nil {
+			return errors.Wrapf(err, "error inserting contribution %s/%s", c.repo, c.sha)
+		}
+	}
+	return errors.Wrap(tx.Commit(), "error committing transaction")
+}
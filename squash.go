@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// squashMergePattern matches the pull request number GitHub appends to the
+// title of a squash merged commit, e.g. "fix a bug (#1234)".
+var squashMergePattern = regexp.MustCompile(`\(#(\d+)\)$`)
+
+// squashAuthor returns the commit attributed to the author of the pull
+// request it was squash merged from, if that differs from the commit
+// author, e.g. when the merger is recorded as the author. The reattributed
+// commit has no author name or email, as those are the merger's. Pull
+// requests are looked up at most once.
+func (s *scanner) squashAuthor(
+	ctx context.Context, ghClient *github.Client, repo string, commit *github.RepositoryCommit,
+) (*github.RepositoryCommit, error) {
+	title := strings.SplitN(commit.GetCommit().GetMessage(), "\n", 2)[0]
+	match := squashMergePattern.FindStringSubmatch(strings.TrimSpace(title))
+	if match == nil {
+		return commit, nil
+	}
+	number, err := strconv.Atoi(match[1])
+	if err != nil {
+		return commit, nil
+	}
+
+	key := fmt.Sprintf("%s#%d", repo, number)
+	s.mu.Lock()
+	author, ok := s.pullAuthors[key]
+	s.mu.Unlock()
+	if !ok {
+		pr, resp, err := ghClient.PullRequests.Get(ctx, s.org, repo, number)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				// The number refers to an issue or a pull request in
+				// another repo.
+				author = nil
+			} else {
+				return nil, errors.Wrapf(err, "error getting pull request %s", key)
+			}
+		} else {
+			author = pr.GetUser()
+		}
+		s.mu.Lock()
+		s.pullAuthors[key] = author
+		s.mu.Unlock()
+	}
+
+	if author == nil || author.GetLogin() == "" || author.GetLogin() == commit.GetAuthor().GetLogin() {
+		return commit, nil
+	}
+	fmt.Printf(
+		"* crediting %s to %s, the author of %s, rather than %s\n",
+		commit.GetSHA(),
+		author.GetLogin(),
+		key,
+		commit.GetAuthor().GetLogin(),
+	)
+	reattributed := *commit
+	reattributed.Author = author
+	gitCommit := *commit.GetCommit()
+	gitCommit.Author = &github.CommitAuthor{Date: commit.GetCommit().GetAuthor().Date}
+	reattributed.Commit = &gitCommit
+	return &reattributed, nil
+}
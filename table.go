@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// escapeTableCell escapes s for use in a Markdown table cell.
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// formatContributorsTable renders ranked contributors as a Markdown table,
+// with a percentage column per -show_percent and first and last
+// contribution columns per -show_span. noun describes what a contribution
+// is and total is the sum of the counts.
+func formatContributorsTable(ranked []rankedContributor, noun string, total int) string {
+	header := []string{"Name", strings.ToUpper(noun[:1]) + noun[1:]}
+	if *flagShowPercent {
+		header = append(header, "Percent")
+	}
+	if *flagShowSpan {
+		header = append(header, "First", "Last")
+	}
+	rows := []string{
+		"| " + strings.Join(header, " | ") + " |",
+		strings.Repeat("| --- ", len(header)) + "|",
+	}
+	for _, entry := range ranked {
+		name := escapeMarkdown(entry.u.name)
+		if entry.u.userURL != "" {
+			name = fmt.Sprintf("[%s](%s)", name, entry.u.userURL)
+		}
+		if email := displayEmail(entry.u.email); email != "" {
+			name += fmt.Sprintf(" (%s)", escapeMarkdown(email))
+		}
+		if company := displayCompany(entry.u.company); company != "" {
+			name += fmt.Sprintf(" (%s)", escapeMarkdown(company))
+		}
+		if location := displayLocation(entry.u.location); location != "" {
+			name += fmt.Sprintf(" (%s)", escapeMarkdown(location))
+		}
+		if entry.u.alumni {
+			name += " *alumni*"
+		}
+		cells := []string{escapeTableCell(name), formatCount(entry.count)}
+		if *flagShowPercent {
+			cells = append(cells, fmt.Sprintf("%.1f%%", 100*float64(entry.count)/float64(total)))
+		}
+		if *flagShowSpan {
+			cells = append(
+				cells,
+				firstContribution(entry.u).Format("2006-01-02"),
+				lastContribution(entry.u).Format("2006-01-02"),
+			)
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+	}
+	return strings.Join(rows, "\n")
+}
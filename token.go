@@ -1,9 +1,15 @@
 package main
 
 import (
-	"fmt"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"runtime"
+	"strings"
 
+	"github.com/cockroachdb/errors"
 	"github.com/google/go-github/v30/github"
 	"golang.org/x/oauth2"
 )
@@ -19,15 +25,124 @@ func (t *tokenSource) Token() (*oauth2.Token, error) {
 	return token, nil
 }
 
-func getGithubClient() (*github.Client, error) {
-	apiKey, ok := os.LookupEnv("GITHUB_API_KEY")
-	if !ok {
-		return nil, fmt.Errorf("cannot find GITHUB_API_KEY")
+const defaultGithubBaseURL = "https://github.com"
+
+// tokenEnvVars are the environment variables a token is read from, in order
+// of precedence. GITHUB_API_KEY is this tool's own; GITHUB_TOKEN and GH_TOKEN
+// are the gh CLI and GitHub Actions conventions.
+var tokenEnvVars = []string{"GITHUB_API_KEY", "GITHUB_TOKEN", "GH_TOKEN"}
+
+// githubToken returns the token in -token_file if set, otherwise the first
+// of tokenEnvVars set.
+func githubToken() (string, error) {
+	if *flagTokenFile != "" {
+		return readTokenFile(*flagTokenFile)
+	}
+	for _, name := range tokenEnvVars {
+		if token := os.Getenv(name); token != "" {
+			return token, nil
+		}
+	}
+	return "", errors.Mark(
+		errors.Newf("cannot find a GitHub token in any of %s", strings.Join(tokenEnvVars, ", ")),
+		ErrUnauthorized,
+	)
+}
+
+// readTokenFile returns the token in path, refusing to use it if the file
+// is readable by anyone other than its owner.
+func readTokenFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "error reading token file %s", path)
+	}
+	// Windows does not have Unix permission bits.
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0044 != 0 {
+		return "", errors.Newf(
+			"token file %s is readable by group or others (mode %s); restrict it with chmod 600",
+			path,
+			info.Mode().Perm(),
+		)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "error reading token file %s", path)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", errors.Mark(errors.Newf("token file %s is empty", path), ErrUnauthorized)
+	}
+	return token, nil
+}
+
+// baseTransport returns the transport requests are ultimately made with.
+// It uses any proxy given by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables and, if caCertPath is set, trusts the PEM encoded
+// CA certificates in it in addition to the system roots.
+func baseTransport(caCertPath string) (http.RoundTripper, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	if caCertPath != "" {
+		pem, err := ioutil.ReadFile(caCertPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", caCertPath)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.Newf("no certificates found in %s", caCertPath)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	return t, nil
+}
+
+// getGithubClient returns an authenticated client. If etags is non-nil,
+// requests are made conditional on the ETags it holds. rng drives the
+// jitter between retries.
+func getGithubClient(etags *etagCache, rng *lockedRand) (*github.Client, error) {
+	apiKey, err := githubToken()
+	if err != nil {
+		return nil, err
 	}
 	tokenSource := &tokenSource{
 		token: apiKey,
 	}
-	oauthClient := oauth2.NewClient(oauth2.NoContext, tokenSource)
+	base, err := baseTransport(*flagCACert)
+	if err != nil {
+		return nil, err
+	}
+	if *flagLogRequests {
+		// The logging transport sits underneath the oauth2 transport so the
+		// logged headers are exactly those sent, with the token redacted.
+		base = &loggingTransport{base: base}
+	}
+	// Headers are set above the logging transport so they are logged.
+	headers := http.Header{}
+	if *flagGithubAPIVersion != "" {
+		headers.Set(githubAPIVersionHeader, *flagGithubAPIVersion)
+	}
+	base = &headerTransport{base: base, headers: headers}
+	if etags != nil {
+		base = &etagTransport{base: base, cache: etags}
+	}
+	base = &retryTransport{base: base, rand: rng}
+	oauthClient := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: oauth2.ReuseTokenSource(nil, tokenSource),
+			Base:   base,
+		},
+	}
 	c := github.NewClient(oauthClient)
+	if base := strings.TrimSuffix(*flagGithubBaseURL, "/"); base != defaultGithubBaseURL {
+		var err error
+		c, err = github.NewEnterpriseClient(base+"/api/v3/", base+"/api/uploads/", oauthClient)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid -github_base_url %q", *flagGithubBaseURL)
+		}
+	}
+	c.UserAgent = *flagUserAgent
 	return c, nil
 }
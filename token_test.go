@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestGithubClientSendsAPIVersion(t *testing.T) {
+	var versions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		versions = append(versions, r.Header.Get(githubAPIVersionHeader))
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}))
+	defer srv.Close()
+
+	baseURL := *flagGithubBaseURL
+	apiVersion := *flagGithubAPIVersion
+	token, hadToken := os.LookupEnv(tokenEnvVars[0])
+	defer func() {
+		*flagGithubBaseURL = baseURL
+		*flagGithubAPIVersion = apiVersion
+		if hadToken {
+			_ = os.Setenv(tokenEnvVars[0], token)
+		} else {
+			_ = os.Unsetenv(tokenEnvVars[0])
+		}
+	}()
+	*flagGithubBaseURL = srv.URL
+	if err := os.Setenv(tokenEnvVars[0], "test-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []string{"2022-11-28", ""} {
+		*flagGithubAPIVersion = version
+		ghClient, err := getGithubClient(nil, newLockedRand(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ghClient.Users.Get(context.Background(), "octocat"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(versions) != 2 || versions[0] != "2022-11-28" || versions[1] != "" {
+		t.Errorf("expected the API version to be sent only when set, got %q", versions)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// loggingTransport logs every request made through it, with any credentials
+// redacted.
+type loggingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var headers []string
+	for k, v := range req.Header {
+		if k == "Authorization" {
+			v = []string{"REDACTED"}
+		}
+		headers = append(headers, fmt.Sprintf("%s: %s", k, strings.Join(v, ",")))
+	}
+	sort.Strings(headers)
+	fmt.Printf("> %s %s [%s]\n", req.Method, req.URL, strings.Join(headers, "; "))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		fmt.Printf("< %s %s: %v\n", req.Method, req.URL, err)
+		return nil, err
+	}
+	fmt.Printf(
+		"< %s %s: %s (rate limit remaining: %s)\n",
+		req.Method,
+		req.URL,
+		resp.Status,
+		resp.Header.Get("X-RateLimit-Remaining"),
+	)
+	return resp, nil
+}
+
+// githubAPIVersionHeader pins the version of the REST API requests are
+// served by, so responses do not change under us as the API evolves.
+const githubAPIVersionHeader = "X-GitHub-Api-Version"
+
+// headerTransport sets the given headers on every request made through it,
+// overriding any already set.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request they are given.
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header[k] = v
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// unlinkedLoginPrefix prefixes the synthetic login given to commit authors
+// whose email is not linked to a GitHub account.
+const unlinkedLoginPrefix = "unlinked:"
+
+func unlinkedLogin(email string) string {
+	return unlinkedLoginPrefix + strings.ToLower(email)
+}
+
+func isUnlinkedLogin(login string) bool {
+	return strings.HasPrefix(login, unlinkedLoginPrefix)
+}
+
+// resolveUnlinked attempts to find the GitHub account of each unlinked
+// author by searching for their email. Commits of resolved authors are
+// moved over to their real login, subject to the same login filters as any
+// other activity, per isExternalLogin. Authors which cannot be resolved keep
+// their synthetic login.
+func (s *scanner) resolveUnlinked(ctx context.Context, ghClient *github.Client) error {
+	for login := range s.userTimes {
+		if !isUnlinkedLogin(login) {
+			continue
+		}
+		email := strings.TrimPrefix(login, unlinkedLoginPrefix)
+		var result *github.UsersSearchResult
+		for {
+			var err error
+			result, _, err = ghClient.Search.Users(ctx, email+" in:email", nil)
+			if err == nil {
+				break
+			}
+			if retry, err := waitForRateLimit(ctx, err); err != nil {
+				return err
+			} else if retry {
+				continue
+			}
+			return errors.Wrapf(err, "error searching users for %s", email)
+		}
+		if len(result.Users) != 1 {
+			continue
+		}
+		resolved := result.Users[0]
+		fmt.Printf("* resolved %s to %s\n", email, resolved.GetLogin())
+
+		times := s.userTimes[login]
+		delete(s.userTimes, login)
+		delete(s.users, login)
+		excluded := s.loginExclusion(resolved.GetLogin())
+		if excluded != "" {
+			s.excluded[excluded] += len(times)
+		} else {
+			s.users[resolved.GetLogin()] = resolved
+			s.userTimes[resolved.GetLogin()] = append(s.userTimes[resolved.GetLogin()], times...)
+		}
+		contributions := s.contributions[:0]
+		for _, c := range s.contributions {
+			if c.login == login {
+				if excluded != "" {
+					continue
+				}
+				c.login = resolved.GetLogin()
+			}
+			contributions = append(contributions, c)
+		}
+		s.contributions = contributions
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+)
+
+// testSearchClient returns a client whose user searches find the login
+// mapped to by the searched email, if any.
+func testSearchClient(t *testing.T, logins map[string]string) *github.Client {
+	return testGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var email string
+		if _, err := fmt.Sscanf(r.URL.Query().Get("q"), "%s in:email", &email); err != nil {
+			t.Errorf("unexpected search query %q", r.URL.Query().Get("q"))
+		}
+		login, ok := logins[email]
+		if !ok {
+			fmt.Fprint(w, `{"total_count": 0, "items": []}`)
+			return
+		}
+		fmt.Fprintf(w, `{"total_count": 1, "items": [{"login": %q}]}`, login)
+	}))
+}
+
+func TestResolveUnlinkedFiltersLogins(t *testing.T) {
+	*flagResolveUnlinked = true
+	defer func() { *flagResolveUnlinked = false }()
+
+	s := testScanner()
+	s.internalLogins = map[string]struct{}{"insider": {}}
+	s.organizationMembers = map[string]*github.User{"member": {Login: github.String("member")}}
+	s.authorsLogins = map[string]struct{}{"janedoe": {}}
+	when := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	for i, email := range []string{
+		"insider@example.com", "member@example.com", "jane@example.com", "outsider@example.com",
+	} {
+		if err := s.processCommit(ctx, nil, "cockroach", testCommit(fmt.Sprint(i), "", email, when)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ghClient := testSearchClient(t, map[string]string{
+		"insider@example.com":  "insider",
+		"member@example.com":   "member",
+		"jane@example.com":     "JaneDoe",
+		"outsider@example.com": "outsider",
+	})
+	if err := s.resolveUnlinked(ctx, ghClient); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.userTimes) != 1 || len(s.userTimes["outsider"]) != 1 {
+		t.Errorf("expected only outsider to be counted, got %v", s.userTimes)
+	}
+	if s.excluded[reasonInternalLogin] != 1 || s.excluded[reasonOrgMember] != 1 || s.excluded[reasonAuthorsLogin] != 1 {
+		t.Errorf("expected an internal login, an org member and an AUTHORS login to be excluded, got %v", s.excluded)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/google/go-github/v30/github"
+)
+
+// userCache memoizes user lookups for the lifetime of the process, so a
+// login appearing in several sections, e.g. both commits and reviews, is
+// only looked up once. Concurrent lookups of the same login wait for the
+// first to finish rather than making their own request.
+type userCache struct {
+	mu      sync.Mutex
+	entries map[string]*userCacheEntry
+}
+
+type userCacheEntry struct {
+	once sync.Once
+	user *github.User
+	err  error
+}
+
+var lookedUpUsers = &userCache{entries: map[string]*userCacheEntry{}}
+
+// get returns the cached user for login, calling fetch to look it up if it
+// has not been already.
+func (c *userCache) get(login string, fetch func() (*github.User, error)) (*github.User, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[login]
+	if !ok {
+		entry = &userCacheEntry{}
+		c.entries[login] = entry
+	}
+	c.mu.Unlock()
+	entry.once.Do(func() {
+		entry.user, entry.err = fetch()
+	})
+	return entry.user, entry.err
+}
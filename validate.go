@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v30/github"
+)
+
+// repoList returns the repos given by -repos, with surrounding whitespace,
+// empty entries, duplicates and -exclude_repos removed.
+func repoList() []string {
+	var repos []string
+	seen := map[string]struct{}{}
+	for _, repo := range strings.Split(*flagExcludeRepos, ",") {
+		seen[strings.ToLower(strings.TrimSpace(repo))] = struct{}{}
+	}
+	for _, repo := range strings.Split(*flagRepos, ",") {
+		repo = strings.TrimSpace(repo)
+		if repo == "" {
+			continue
+		}
+		if _, ok := seen[strings.ToLower(repo)]; ok {
+			continue
+		}
+		seen[strings.ToLower(repo)] = struct{}{}
+		repos = append(repos, repo)
+	}
+	return repos
+}
+
+// renamedRepos maps the current names of renamed repos found by
+// validateTargets to the names they were given as.
+var renamedRepos = map[string]string{}
+
+// validateTargets checks that the organization and, if checkRepos is set,
+// each of the repos exist, returning an error listing every one which does
+// not. Requests for a renamed repo are redirected to it, so a repo found
+// under a different name has been renamed; the current name of each such
+// repo is returned, keyed by the name given.
+func validateTargets(
+	ctx context.Context, ghClient *github.Client, org string, repos []string, checkRepos bool,
+) (map[string]string, error) {
+	var problems []string
+	if _, resp, err := ghClient.Organizations.Get(ctx, org); err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return nil, errors.Wrapf(markGithubError(err), "error checking organization %s", org)
+		}
+		// Without the organization no repo can be found either.
+		return nil, errors.Newf("organization %q does not exist", org)
+	}
+	renames := map[string]string{}
+	if checkRepos {
+		for _, repo := range repos {
+			r, resp, err := ghClient.Repositories.Get(ctx, org, repo)
+			if err != nil {
+				if resp == nil || resp.StatusCode != http.StatusNotFound {
+					return nil, errors.Wrapf(markGithubError(err), "error checking repo %s/%s", org, repo)
+				}
+				problems = append(problems, org+"/"+repo)
+				continue
+			}
+			// Names are case insensitive.
+			if r.GetName() != "" && !strings.EqualFold(r.GetName(), repo) {
+				renames[repo] = r.GetName()
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.Mark(
+			errors.Newf("repos do not exist: %s", strings.Join(problems, ", ")),
+			ErrRepoNotFound,
+		)
+	}
+	return renames, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestValidateTargetsRenamedRepo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/cockroachdb", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"login": "cockroachdb"}`)
+	})
+	mux.HandleFunc("/repos/cockroachdb/old-name", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/repositories/1234", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/repositories/1234", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1234, "name": "new-name"}`)
+	})
+	mux.HandleFunc("/repos/cockroachdb/Same-Name", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 5678, "name": "same-name"}`)
+	})
+	ghClient := testGithubClient(t, mux)
+
+	ctx := context.Background()
+	renames, err := validateTargets(ctx, ghClient, "cockroachdb", []string{"old-name", "Same-Name"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renames) != 1 || renames["old-name"] != "new-name" {
+		t.Errorf("expected only old-name to be renamed to new-name, got %v", renames)
+	}
+
+	_, err = validateTargets(ctx, ghClient, "cockroachdb", []string{"missing"}, true)
+	if !errors.Is(err, ErrRepoNotFound) {
+		t.Errorf("expected a missing repo to be reported as not found, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// isActive returns whether the user contributed within -active_window of
+// asOf.
+func isActive(u user, asOf time.Time) bool {
+	return !lastContribution(u).Before(asOf.Add(-*flagActiveWindow))
+}
+
+// formatVeterans lists up to n of the still active users with the earliest
+// first contributions, earliest first.
+func formatVeterans(users map[string]user, asOf time.Time, n int) string {
+	var veterans []user
+	for _, u := range users {
+		if len(u.times) > 0 && isActive(u, asOf) {
+			veterans = append(veterans, u)
+		}
+	}
+	sort.Slice(veterans, func(i, j int) bool {
+		fi, fj := firstContribution(veterans[i]), firstContribution(veterans[j])
+		if fi.Equal(fj) {
+			return veterans[i].login < veterans[j].login
+		}
+		return fi.Before(fj)
+	})
+	if len(veterans) > n {
+		veterans = veterans[:n]
+	}
+	var ret []string
+	for _, u := range veterans {
+		name := escapeMarkdown(u.name)
+		if u.userURL != "" {
+			name = fmt.Sprintf("[%s](%s)", name, u.userURL)
+		}
+		ret = append(ret, fmt.Sprintf("%s (since %d)", name, firstContribution(u).Year()))
+	}
+	return strings.Join(ret, ", ")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// parseOutputYears parses a comma separated list of years and inclusive
+// year ranges, e.g. "2019,2022-2024", checking every year is between start
+// and end.
+func parseOutputYears(s string, start time.Time, end time.Time) (map[int]struct{}, error) {
+	years := map[int]struct{}{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		from, to := entry, entry
+		if i := strings.Index(entry, "-"); i >= 0 {
+			from, to = entry[:i], entry[i+1:]
+		}
+		fromYear, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, errors.Newf("invalid year %q", entry)
+		}
+		toYear, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return nil, errors.Newf("invalid year %q", entry)
+		}
+		if fromYear > toYear {
+			return nil, errors.Newf("invalid year range %q", entry)
+		}
+		if fromYear < start.Year() || toYear > end.Year() {
+			return nil, errors.Newf(
+				"years %q are outside of the scanned range %d-%d",
+				entry,
+				start.Year(),
+				end.Year(),
+			)
+		}
+		for year := fromYear; year <= toYear; year++ {
+			years[year] = struct{}{}
+		}
+	}
+	return years, nil
+}
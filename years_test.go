@@ -0,0 +1,39 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputYearsRestrictsJSONLAndChart(t *testing.T) {
+	*flagOutputYears = "2019-2020"
+	defer func() { *flagOutputYears = "" }()
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	jsonl, err := formatJSONL(testUsers(), start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var years []string
+	for _, m := range regexp.MustCompile(`"year":(\d+)`).FindAllStringSubmatch(jsonl, -1) {
+		years = append(years, m[1])
+	}
+	if got, expected := strings.Join(years, ","), "2019,2020,2020,2020"; got != expected {
+		t.Errorf("JSONL records are for years %s, expected %s", got, expected)
+	}
+
+	html, err := formatHTML(testUsers(), start, end, "GENERATED_AT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, m := range regexp.MustCompile(`<text [^>]*>(\d+)</text>`).FindAllStringSubmatch(html, -1) {
+		labels = append(labels, m[1])
+	}
+	if got, expected := strings.Join(labels, ","), "2019,2020"; got != expected {
+		t.Errorf("chart has bars for %s, expected %s", got, expected)
+	}
+}